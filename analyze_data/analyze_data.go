@@ -10,27 +10,17 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"procedural-map-generation-toolkit/backend/generator"
+	_ "procedural-map-generation-toolkit/backend/generator/methods"
+	"procedural-map-generation-toolkit/backend/metrics"
+	"procedural-map-generation-toolkit/backend/tiles"
 )
 
 const (
 	outputDir = "output_maps" // Match the output directory of the generation script
 )
 
-// GenerateRequest structure from the generation script
-type GenerateRequest struct {
-	GenerationMethod string  `json:"generationMethod"`
-	Width            int     `json:"width"`
-	Height           int     `json:"height"`
-	Iterations       int     `json:"iterations,omitempty"`
-	RandomnessFactor float64 `json:"randomnessFactor,omitempty"`
-	PaintedTiles     [][]int `json:"paintedTiles,omitempty"`
-	NoiseScale       float64 `json:"noiseScale,omitempty"`
-	NoiseOctaves     int     `json:"noiseOctaves,omitempty"`
-	NoisePersistence float64 `json:"noisePersistence,omitempty"`
-	NoiseLacunarity  float64 `json:"noiseLacunarity,omitempty"`
-	WFCSeed          *int64  `json:"wfcSeed,omitempty"`
-}
-
 // GenerateResponse structure from the generation script
 type GenerateResponse struct {
 	Grid        [][]int                   `json:"grid"`
@@ -45,10 +35,10 @@ type GenerateResponse struct {
 
 // ResultData structure from the generation script
 type ResultData struct {
-	RequestParams    GenerateRequest  `json:"requestParams"`
-	ResponseMetrics  GenerateResponse `json:"responseMetrics"`
-	GenerationTimeMs int64            `json:"generationTimeMs"`
-	FilePath         string           `json:"-"` // Internal, not from JSON
+	RequestParams    generator.Request `json:"requestParams"`
+	ResponseMetrics  GenerateResponse  `json:"responseMetrics"`
+	GenerationTimeMs int64             `json:"generationTimeMs"`
+	FilePath         string            `json:"-"` // Internal, not from JSON
 }
 
 // DataPoint for the CSV output of individual records
@@ -76,9 +66,14 @@ type MethodAverageData struct {
 }
 
 func main() {
-	methods := []string{"mlca", "noise", "wfc"}
+	methods := generator.Names()
 	var allResults []ResultData
 
+	// Tile sets beyond the built-in ones, as loaded by the server
+	if _, err := tiles.LoadDir("tilesets"); err != nil {
+		log.Printf("Error loading tile sets: %v", err)
+	}
+
 	log.Println("Starting to read generated map data...")
 
 	for _, method := range methods {
@@ -128,13 +123,19 @@ func main() {
 			FractalDim: res.ResponseMetrics.FractalDim,
 		}
 
-		var currentLandRatio float64
-		for i := 3; i <= 7; i++ { // Tile types 3 (WetSand) through 7 (Forest)
-			if freq, ok := res.ResponseMetrics.Frequencies[strconv.Itoa(i)]; ok {
-				currentLandRatio += freq
+		set, ok := tiles.Lookup(res.RequestParams.TileSet)
+		if !ok {
+			log.Printf("Unknown tile set %q in %s, assuming %s.", res.RequestParams.TileSet, res.FilePath, tiles.Default.Name)
+			set = tiles.Default
+		}
+		frequencies := make(map[int]float64, len(res.ResponseMetrics.Frequencies))
+		for key, freq := range res.ResponseMetrics.Frequencies {
+			if t, err := strconv.Atoi(key); err == nil {
+				frequencies[t] = freq
 			}
 		}
-		point.LandRatio = currentLandRatio
+		// Everything but water, from wet sand up by default
+		point.LandRatio = metrics.LandRatioFromFrequencies(frequencies, set)
 
 		uniquePairs := make(map[string]bool)
 		if res.ResponseMetrics.Adjacency != nil {
@@ -0,0 +1,279 @@
+package caves
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"procedural-map-generation-toolkit/backend/gol"
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// Repair selects what happens to floor regions cut off from the largest one.
+type Repair string
+
+const (
+	RepairConnect Repair = "connect" // carve tunnels to the main cave
+	RepairRemove  Repair = "remove"  // fill them with wall
+	RepairNone    Repair = "none"    // leave them
+)
+
+// ParseRepair maps a request value to a Repair. Empty gives RepairConnect.
+func ParseRepair(s string) (Repair, error) {
+	switch r := Repair(s); r {
+	case "":
+		return RepairConnect, nil
+	case RepairConnect, RepairRemove, RepairNone:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown cave repair %q", s)
+}
+
+// Options controls Generate. Unset fields give a 45% wall fill, no smoothing
+// and connected pockets; Wall and Floor must differ.
+type Options struct {
+	// Wall and Floor are the tile types of rock and open cave.
+	Wall, Floor tiles.TileType
+	// Fill is the initial share of wall cells; nil means 0.45.
+	Fill *float64
+	// Smoothing is the number of 4-5 rule passes.
+	Smoothing int
+	Repair    Repair
+	// MinRegionSize removes pockets smaller than this many cells instead of
+	// connecting them.
+	MinRegionSize int
+	// SnapshotEvery records the grid every k smoothing passes, together with
+	// the initial and the repaired grid. Zero disables recording.
+	SnapshotEvery int
+	// Workers is the number of goroutines smoothing row bands; zero uses all CPUs.
+	Workers int
+	// Progress, when set, is called after every smoothing pass.
+	Progress func(done, total int)
+	// Watch, when set, receives the grid after every smoothing pass; see
+	// gol.Options.Watch.
+	Watch func(grid [][]gol.Tile)
+}
+
+// Stats reports the floor regions (4-connected) before and after repair.
+type Stats struct {
+	RegionsBefore int `json:"regionsBefore"`
+	RegionsAfter  int `json:"regionsAfter"`
+	TunnelCells   int `json:"tunnelCells"`
+	RemovedCells  int `json:"removedCells"`
+	// Frames holds the recorded grids when SnapshotEvery is set
+	Frames [][][]gol.Tile `json:"-"`
+}
+
+// The classic cave smoothing rule: a cell becomes wall with at least five wall
+// neighbors and stays wall with at least four.
+const smoothingRule = "B5678/S45678"
+
+// Generate builds a width x height cave map from rng. It stops with
+// ctx.Err() when ctx is canceled.
+func Generate(ctx context.Context, width, height int, rng *rand.Rand, opts Options) ([][]gol.Tile, Stats, error) {
+	var stats Stats
+	if opts.Wall == opts.Floor {
+		return nil, stats, fmt.Errorf("wall and floor tiles must differ")
+	}
+	fill := 0.45
+	if opts.Fill != nil {
+		fill = *opts.Fill
+	}
+	if opts.Repair == "" {
+		opts.Repair = RepairConnect
+	}
+
+	// Walled border so caves do not open onto the map edge
+	grid, err := gol.InitializeGrid(width, height, rng, gol.InitOptions{
+		Alive:   opts.Wall,
+		Dead:    opts.Floor,
+		Density: &fill,
+		Pattern: gol.PatternBorder,
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+	rule, err := gol.ParseLifeRule(smoothingRule)
+	if err != nil {
+		return nil, stats, err
+	}
+	// Beyond the map edge is rock
+	grid, stats.Frames, err = gol.ApplyCARules(ctx, grid, rule.Rules(opts.Wall, opts.Floor), opts.Smoothing, gol.Options{
+		SnapshotEvery: opts.SnapshotEvery,
+		Workers:       opts.Workers,
+		Progress:      opts.Progress,
+		Watch:         opts.Watch,
+		Boundary:      neighborhood.BoundaryFixed,
+		BoundaryTile:  opts.Wall,
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, stats, err
+	}
+	regions := floorRegions(grid, opts.Wall)
+	stats.RegionsBefore = len(regions)
+	if len(regions) > 1 && opts.Repair != RepairNone {
+		c := newConnector(grid, regions[0], opts.Wall)
+		for _, r := range regions[1:] {
+			if c.connected[r[0].y][r[0].x] {
+				// Joined by an earlier tunnel that crossed it
+				continue
+			}
+			if opts.Repair == RepairRemove || len(r) < opts.MinRegionSize {
+				for _, p := range r {
+					grid[p.y][p.x].State = opts.Wall
+				}
+				stats.RemovedCells += len(r)
+				continue
+			}
+			stats.TunnelCells += c.connect(r, opts.Floor)
+		}
+	}
+	stats.RegionsAfter = len(floorRegions(grid, opts.Wall))
+	if opts.SnapshotEvery > 0 && stats.TunnelCells+stats.RemovedCells > 0 {
+		stats.Frames = append(stats.Frames, cloneGrid(grid))
+	}
+	return grid, stats, nil
+}
+
+type point struct{ x, y int }
+
+var steps = []point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+// floorRegions returns the 4-connected non-wall regions, largest first.
+func floorRegions(grid [][]gol.Tile, wall tiles.TileType) [][]point {
+	height, width := len(grid), len(grid[0])
+	seen := make([][]bool, height)
+	for y := range seen {
+		seen[y] = make([]bool, width)
+	}
+	var regions [][]point
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if seen[y][x] || grid[y][x].State == wall {
+				continue
+			}
+			// Flood fill
+			seen[y][x] = true
+			region := []point{{x, y}}
+			for i := 0; i < len(region); i++ {
+				for _, s := range steps {
+					n := point{region[i].x + s.x, region[i].y + s.y}
+					if n.x < 0 || n.x >= width || n.y < 0 || n.y >= height || seen[n.y][n.x] || grid[n.y][n.x].State == wall {
+						continue
+					}
+					seen[n.y][n.x] = true
+					region = append(region, n)
+				}
+			}
+			regions = append(regions, region)
+		}
+	}
+	sort.SliceStable(regions, func(i, j int) bool { return len(regions[i]) > len(regions[j]) })
+	return regions
+}
+
+// connector carves tunnels from pockets to the main cave. The mask of cells
+// connected to the main cave is built once and grows as pockets join, and
+// the search buffers are shared by all pockets.
+type connector struct {
+	grid          [][]gol.Tile
+	wall          tiles.TileType
+	width, height int
+	connected     [][]bool
+	// visited[y][x] == round marks the cells the current search reached,
+	// which saves clearing it for every pocket
+	visited [][]int
+	round   int
+	// from[y][x] is the cell the search came from; search starts point to
+	// themselves
+	from  [][]point
+	queue []point
+}
+
+func newConnector(grid [][]gol.Tile, main []point, wall tiles.TileType) *connector {
+	height, width := len(grid), len(grid[0])
+	c := &connector{
+		grid: grid, wall: wall, width: width, height: height,
+		connected: make([][]bool, height),
+		visited:   make([][]int, height),
+		from:      make([][]point, height),
+	}
+	for y := 0; y < height; y++ {
+		c.connected[y] = make([]bool, width)
+		c.visited[y] = make([]int, width)
+		c.from[y] = make([]point, width)
+	}
+	for _, p := range main {
+		c.connected[p.y][p.x] = true
+	}
+	return c
+}
+
+// connect carves the shortest 4-connected tunnel from region to the main
+// cave and returns the number of wall cells turned to floor.
+func (c *connector) connect(region []point, floor tiles.TileType) int {
+	// Breadth-first search from the region through rock until the main cave is reached
+	c.round++
+	c.queue = c.queue[:0]
+	for _, p := range region {
+		c.visited[p.y][p.x] = c.round
+		c.from[p.y][p.x] = p
+		c.queue = append(c.queue, p)
+	}
+	for i := 0; i < len(c.queue); i++ {
+		p := c.queue[i]
+		for _, s := range steps {
+			// Keep the outer wall intact
+			n := point{p.x + s.x, p.y + s.y}
+			if n.x < 1 || n.x >= c.width-1 || n.y < 1 || n.y >= c.height-1 || c.visited[n.y][n.x] == c.round {
+				continue
+			}
+			c.visited[n.y][n.x] = c.round
+			c.from[n.y][n.x] = p
+			if !c.connected[n.y][n.x] {
+				c.queue = append(c.queue, n)
+				continue
+			}
+			carved := 0
+			for cur := p; c.from[cur.y][cur.x] != cur; cur = c.from[cur.y][cur.x] {
+				if c.grid[cur.y][cur.x].State == c.wall {
+					c.grid[cur.y][cur.x].State = floor
+					carved++
+				}
+			}
+			// The path may cross other pockets, which join the cave as well
+			c.join(region[0])
+			return carved
+		}
+	}
+	return 0
+}
+
+// join marks the floor cells 4-connected to p as connected.
+func (c *connector) join(p point) {
+	c.connected[p.y][p.x] = true
+	c.queue = append(c.queue[:0], p)
+	for i := 0; i < len(c.queue); i++ {
+		for _, s := range steps {
+			n := point{c.queue[i].x + s.x, c.queue[i].y + s.y}
+			if n.x >= 0 && n.x < c.width && n.y >= 0 && n.y < c.height && !c.connected[n.y][n.x] && c.grid[n.y][n.x].State != c.wall {
+				c.connected[n.y][n.x] = true
+				c.queue = append(c.queue, n)
+			}
+		}
+	}
+}
+
+func cloneGrid(grid [][]gol.Tile) [][]gol.Tile {
+	out := make([][]gol.Tile, len(grid))
+	for y := range grid {
+		out[y] = append([]gol.Tile(nil), grid[y]...)
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package caves
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		width, height int
+		fill          float64
+		repair        Repair
+		minRegionSize int
+	}{
+		{40, 30, 0.45, RepairConnect, 0},
+		{64, 64, 0.5, RepairConnect, 0},
+		{80, 40, 0.55, RepairConnect, 0},
+		{64, 64, 0.5, RepairConnect, 20},
+		{64, 64, 0.5, RepairRemove, 0},
+		{64, 64, 0.5, RepairNone, 0},
+	}
+	for _, tt := range tests {
+		for seed := int64(1); seed <= 5; seed++ {
+			name := fmt.Sprintf("%dx%d/fill=%g/%s/min=%d/seed=%d", tt.width, tt.height, tt.fill, tt.repair, tt.minRegionSize, seed)
+			t.Run(name, func(t *testing.T) {
+				fill := tt.fill
+				grid, stats, err := Generate(context.Background(), tt.width, tt.height, rand.New(rand.NewSource(seed)), Options{
+					Wall:          tiles.Forest,
+					Floor:         tiles.Sand,
+					Smoothing:     5,
+					Fill:          &fill,
+					Repair:        tt.repair,
+					MinRegionSize: tt.minRegionSize,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				regions := len(floorRegions(grid, tiles.Forest))
+				if stats.RegionsAfter != regions {
+					t.Errorf("RegionsAfter = %d, grid has %d regions", stats.RegionsAfter, regions)
+				}
+				switch {
+				case tt.repair == RepairNone:
+					if regions != stats.RegionsBefore {
+						t.Errorf("repair none left %d of %d regions", regions, stats.RegionsBefore)
+					}
+				case stats.RegionsBefore > 0 && regions != 1:
+					t.Errorf("RegionsAfter = %d, want 1 (RegionsBefore = %d)", regions, stats.RegionsBefore)
+				}
+				if tt.repair == RepairRemove && stats.TunnelCells != 0 {
+					t.Errorf("repair remove carved %d tunnel cells", stats.TunnelCells)
+				}
+			})
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		fill float64
+		// floor is the expected number of floor cells; -1 skips the check
+		floor, regions int
+	}{
+		{0, 18 * 8, 1}, // only the walled border
+		{1, 0, 0},
+		{0.45, -1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.fill), func(t *testing.T) {
+			fill := tt.fill
+			// Without smoothing the grid is the initial fill plus repair
+			grid, stats, err := Generate(context.Background(), 20, 10, rand.New(rand.NewSource(1)), Options{Wall: tiles.Forest, Floor: tiles.Sand, Fill: &fill})
+			if err != nil {
+				t.Fatal(err)
+			}
+			floor := 0
+			for _, row := range grid {
+				for _, c := range row {
+					if c.State == tiles.Sand {
+						floor++
+					}
+				}
+			}
+			if tt.floor >= 0 && floor != tt.floor {
+				t.Errorf("%d floor cells, want %d", floor, tt.floor)
+			}
+			if stats.RegionsAfter != tt.regions {
+				t.Errorf("RegionsAfter = %d, want %d", stats.RegionsAfter, tt.regions)
+			}
+		})
+	}
+}
+
+func TestTiles(t *testing.T) {
+	// Tile 0 is honoured as wall
+	grid, _, err := Generate(context.Background(), 20, 10, rand.New(rand.NewSource(1)), Options{Wall: tiles.DeepWater, Floor: tiles.Grass})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grid[0][0].State != tiles.DeepWater {
+		t.Errorf("border tile %d, want deep water", grid[0][0].State)
+	}
+	for _, opts := range []Options{{}, {Wall: tiles.Sand, Floor: tiles.Sand}} {
+		if _, _, err := Generate(context.Background(), 20, 10, rand.New(rand.NewSource(1)), opts); err == nil {
+			t.Errorf("Generate(%+v) succeeded, want an error", opts)
+		}
+	}
+}
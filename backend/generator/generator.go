@@ -0,0 +1,231 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"procedural-map-generation-toolkit/backend/mlca"
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// DefaultSeed is used when a request does not set a seed.
+const DefaultSeed = 1
+
+// Generator is a map generation method.
+type Generator interface {
+	// Name is the generationMethod value selecting the generator.
+	Name() string
+	// Description is a one-line summary for users.
+	Description() string
+	// Params lists the request fields the generator reads.
+	Params() []Param
+	// Generate runs the method. It returns ctx.Err() once ctx is canceled and
+	// reports to progress while it runs.
+	Generate(ctx context.Context, req *Request, progress Progress) (*Result, error)
+}
+
+// Progress receives updates from a running generator. Either func may be nil.
+type Progress struct {
+	// Step reports the steps done out of total, e.g. iterations or collapsed
+	// cells.
+	Step func(done, total int)
+	// Frame receives the current grid after a step. WFC marks undecided cells
+	// with -1.
+	Frame func(grid Grid)
+}
+
+// Sampler is implemented by generators that define a parameter sweep for
+// batch runs.
+type Sampler interface {
+	Samples(n int) []Sample
+}
+
+// Sample is one request of a batch sweep; Name is unique within the sweep and
+// suitable as a file name.
+type Sample struct {
+	Name    string
+	Request Request
+}
+
+// Result is the output of a generator.
+type Result struct {
+	Grid Grid
+	// Frames holds recorded grids when snapshotEvery is set
+	Frames []Grid
+	// Stats holds method-specific statistics, e.g. MLCA iteration counts
+	Stats any
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Generator{}
+)
+
+// Register adds g to the registry. It panics when the name is taken, as
+// registration happens in init functions.
+func Register(g Generator) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := registry[g.Name()]; dup {
+		panic(fmt.Sprintf("generator %q registered twice", g.Name()))
+	}
+	registry[g.Name()] = g
+}
+
+// Lookup returns the generator registered under name.
+func Lookup(name string) (Generator, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	g, ok := registry[name]
+	return g, ok
+}
+
+// All returns the registered generators sorted by name.
+func All() []Generator {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make([]Generator, 0, len(registry))
+	for _, g := range registry {
+		all = append(all, g)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name() < all[j].Name() })
+	return all
+}
+
+// Names returns the names of the registered generators, sorted.
+func Names() []string {
+	all := All()
+	names := make([]string, len(all))
+	for i, g := range all {
+		names[i] = g.Name()
+	}
+	return names
+}
+
+// Request holds the parameters of every generator in one flat structure, so
+// that it can be decoded from a single JSON object. Each generator reads the
+// fields listed in its Params.
+type Request struct {
+	GenerationMethod string  `json:"generationMethod"`
+	Width            int     `json:"width"`
+	Height           int     `json:"height"`
+	Iterations       *int    `json:"iterations,omitempty"`
+	RandomnessFactor float64 `json:"randomnessFactor"`
+	PrevGrid         [][]int `json:"prevGrid"`
+	PaintedTiles     [][]int `json:"paintedTiles"`
+	NoiseScale       float64 `json:"noiseScale"`
+	NoiseOctaves     *int    `json:"noiseOctaves,omitempty"`
+	NoisePersistence float64 `json:"noisePersistence"`
+	NoiseLacunarity  float64 `json:"noiseLacunarity"`
+	WFCSeed          *int64  `json:"wfcSeed,omitempty"`
+
+	// Tile set the grid values refer to, by name; empty is tiles.Default
+	TileSet string `json:"tileSet,omitempty"`
+
+	// Seed for all methods; wfcSeed takes precedence for WFC
+	Seed *int64 `json:"seed,omitempty"`
+	// With prevGrid, WFC keeps the grid and re-solves illegal adjacencies plus this margin (default 1)
+	WFCRepairRadius int `json:"wfcRepairRadius,omitempty"`
+
+	// MLCA rule set and conflict resolution; empty rules use mlca.CreateDefaultRules
+	Rules         []mlca.TerrainRule `json:"rules,omitempty"`
+	RuleSelection string             `json:"ruleSelection,omitempty"`
+
+	// Cellular automaton neighborhood for MLCA and GOL: "moore", "vonneumann" or "custom"
+	Neighborhood       string                `json:"neighborhood,omitempty"`
+	NeighborhoodRadius int                   `json:"neighborhoodRadius,omitempty"`
+	NeighborhoodKernel []neighborhood.Offset `json:"neighborhoodKernel,omitempty"`
+
+	// MLCA edge handling: "fixed" (default, with boundaryTile), "clamp", "mirror", "wrap" or "ignore"
+	Boundary     string `json:"boundary,omitempty"`
+	BoundaryTile *int   `json:"boundaryTile,omitempty"`
+
+	// MLCA early stopping on a stable/oscillating grid or a low fraction of changed cells
+	StopOnConvergence bool    `json:"stopOnConvergence,omitempty"`
+	MinChangeFraction float64 `json:"minChangeFraction,omitempty"`
+
+	// Time-lapse for MLCA and GOL: record every k iterations, encoded as "full", "delta" or "gif"
+	SnapshotEvery    int    `json:"snapshotEvery,omitempty"`
+	SnapshotEncoding string `json:"snapshotEncoding,omitempty"`
+
+	// Rendered image added to the response: "png" or "svg", with pixels per tile (default 8)
+	Image         string `json:"image,omitempty"`
+	ImageCellSize int    `json:"imageCellSize,omitempty"`
+
+	// MLCA cells that rules may not overwrite ("hard" painted constraints)
+	LockedTiles [][]bool `json:"lockedTiles,omitempty"`
+
+	// GOL rule as a preset name or B/S notation (default "B3/S23") and the tile types of live and dead cells
+	GOLRule   string `json:"golRule,omitempty"`
+	AliveTile *int   `json:"aliveTile,omitempty"`
+	DeadTile  *int   `json:"deadTile,omitempty"`
+
+	// GOL initial grid: share of live cells (default 0.5) and "random", "noise" or "border" fill
+	GOLDensity    *float64 `json:"golDensity,omitempty"`
+	GOLPattern    string   `json:"golPattern,omitempty"`
+	GOLNoiseScale float64  `json:"golNoiseScale,omitempty"`
+
+	// GOL states for Generations rules (alive, decay..., dead) or golRule "cyclic", and the cyclic threshold (default 3)
+	GOLStates    []int `json:"golStates,omitempty"`
+	GOLThreshold int   `json:"golThreshold,omitempty"`
+
+	// Caves: initial wall share (default 0.45), "connect", "remove" or "none" for cut-off
+	// pockets, pockets below caveMinRegion cells are removed; iterations are smoothing passes
+	CaveFill      *float64 `json:"caveFill,omitempty"`
+	CaveRepair    string   `json:"caveRepair,omitempty"`
+	CaveMinRegion int      `json:"caveMinRegion,omitempty"`
+	WallTile      *int     `json:"wallTile,omitempty"`
+	FloorTile     *int     `json:"floorTile,omitempty"`
+}
+
+// SeedOr returns the request seed, or def when none is given.
+func (r *Request) SeedOr(def int64) int64 {
+	if r.Seed != nil {
+		return *r.Seed
+	}
+	return def
+}
+
+// IterationsOr returns the request iterations, or def when none are given.
+func (r *Request) IterationsOr(def int) int {
+	if r.Iterations != nil {
+		return *r.Iterations
+	}
+	return def
+}
+
+// Tiles returns the tile set of the request, or tiles.Default for unknown
+// names, which Validate rejects.
+func (r *Request) Tiles() *tiles.Set {
+	if set, ok := tiles.Lookup(r.TileSet); ok {
+		return set
+	}
+	return tiles.Default
+}
+
+// TileParam validates an optional tile type request field. Without a value it
+// returns the set's default for field, or def.
+func TileParam(v *int, def tiles.TileType, set *tiles.Set, field string) (tiles.TileType, error) {
+	if v == nil {
+		return set.Default(field, def), nil
+	}
+	if !set.Valid(*v) {
+		return 0, fmt.Errorf("%s %d is not a tile of set %q", field, *v, set.Name)
+	}
+	return tiles.TileType(*v), nil
+}
+
+// Info describes a generator for clients.
+type Info struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Params      []Param `json:"params"`
+}
+
+// Describe returns the client-facing description of g.
+func Describe(g Generator) Info {
+	return Info{Name: g.Name(), Description: g.Description(), Params: g.Params()}
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/mlca"
+)
+
+// named is a stub generator registered under its own name.
+type named struct {
+	stub
+	name string
+}
+
+func (n named) Name() string { return n.name }
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"zeta", "alpha"} {
+		Register(named{name: name})
+	}
+	if g, ok := Lookup("alpha"); !ok || g.Name() != "alpha" {
+		t.Errorf("Lookup(alpha) = %v, %t", g, ok)
+	}
+	if _, ok := Lookup("missing"); ok {
+		t.Error("Lookup(missing) found a generator")
+	}
+	if names := Names(); !slices.IsSorted(names) || !slices.Contains(names, "alpha") || !slices.Contains(names, "zeta") {
+		t.Errorf("Names() = %v, want both generators sorted", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice did not panic")
+		}
+	}()
+	Register(named{name: "alpha"})
+}
+
+func TestDescribe(t *testing.T) {
+	g := stub{ParamIterations}
+	want := Info{Name: "stub", Description: "test generator", Params: []Param{ParamIterations}}
+	if got := Describe(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("Describe() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	seed := int64(1)
+	g := stub(Common())
+	tests := []struct {
+		name string
+		req  Request
+		want []string
+	}{
+		{"common fields", Request{GenerationMethod: "stub", Width: 4, Height: 4, Seed: &seed}, nil},
+		{"empty slices", Request{Width: 4, PrevGrid: [][]int{}, Rules: []mlca.TerrainRule{}}, nil},
+		{"foreign fields", Request{Width: 4, Iterations: intp(3), PrevGrid: [][]int{{0}}}, []string{"iterations", "prevGrid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unsupported(g, &tt.req); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unsupported() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"errors"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// Grid is the tile grid shared by all generators, indexed [y][x].
+type Grid [][]tiles.TileType
+
+// NewGrid returns a width x height grid of DeepWater.
+func NewGrid(width, height int) Grid {
+	g := make(Grid, height)
+	for y := range g {
+		g[y] = make([]tiles.TileType, width)
+	}
+	return g
+}
+
+// GridFromInts converts a request grid, checking that it is rectangular.
+func GridFromInts(values [][]int) (Grid, error) {
+	g := make(Grid, len(values))
+	for y, row := range values {
+		if len(row) != len(values[0]) {
+			return nil, errors.New("grid rows differ in length")
+		}
+		g[y] = make([]tiles.TileType, len(row))
+		for x, v := range row {
+			g[y][x] = tiles.TileType(v)
+		}
+	}
+	return g, nil
+}
+
+// Width is the length of the rows, zero for an empty grid.
+func (g Grid) Width() int {
+	if len(g) == 0 {
+		return 0
+	}
+	return len(g[0])
+}
+
+// Height is the number of rows.
+func (g Grid) Height() int {
+	return len(g)
+}
+
+// Ints returns the grid as tile type values, as used in responses and metrics.
+func (g Grid) Ints() [][]int {
+	out := make([][]int, len(g))
+	for y, row := range g {
+		out[y] = make([]int, len(row))
+		for x, t := range row {
+			out[y][x] = int(t)
+		}
+	}
+	return out
+}
+
+// Clone returns a deep copy of the grid.
+func (g Grid) Clone() Grid {
+	out := make(Grid, len(g))
+	for y := range g {
+		out[y] = append([]tiles.TileType(nil), g[y]...)
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"procedural-map-generation-toolkit/backend/caves"
+	"procedural-map-generation-toolkit/backend/generator"
+	"procedural-map-generation-toolkit/backend/gol"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func init() {
+	generator.Register(cavesGenerator{})
+}
+
+type cavesGenerator struct{}
+
+func (cavesGenerator) Name() string { return "caves" }
+
+func (cavesGenerator) Description() string {
+	return "Cellular-automaton caves smoothed by the 4-5 rule, with cut-off pockets connected or removed"
+}
+
+func (cavesGenerator) Params() []generator.Param {
+	return append(generator.Common(),
+		generator.Param{Name: "iterations", Type: "int", Description: "Smoothing passes", Default: 5},
+		generator.Param{Name: "caveFill", Type: "float", Description: "Initial share of wall cells", Default: 0.45}.WithRange(0, 1),
+		generator.Param{Name: "caveRepair", Type: "string", Description: "What happens to pockets cut off from the main cave",
+			Default: string(caves.RepairConnect), Enum: []string{string(caves.RepairConnect), string(caves.RepairRemove), string(caves.RepairNone)}},
+		generator.Param{Name: "caveMinRegion", Type: "int", Description: "Pockets smaller than this are filled in"}.WithRange(0, 1<<20),
+		generator.Param{Name: "wallTile", Type: "tile", Description: "Tile of rock", Default: int(tiles.Forest)},
+		generator.Param{Name: "floorTile", Type: "tile", Description: "Tile of open cave", Default: int(tiles.Sand)},
+		generator.ParamSnapshotEvery,
+		generator.ParamSnapshotEncoding,
+	)
+}
+
+func (cavesGenerator) Generate(ctx context.Context, req *generator.Request, progress generator.Progress) (*generator.Result, error) {
+	repair, err := caves.ParseRepair(req.CaveRepair)
+	if err != nil {
+		return nil, err
+	}
+	opts := caves.Options{
+		Fill:          req.CaveFill,
+		Smoothing:     req.IterationsOr(5),
+		Repair:        repair,
+		MinRegionSize: req.CaveMinRegion,
+		SnapshotEvery: req.SnapshotEvery,
+		Progress:      progress.Step,
+	}
+	if progress.Frame != nil {
+		opts.Watch = func(grid [][]gol.Tile) { progress.Frame(fromGOL(grid)) }
+	}
+	set := req.Tiles()
+	if opts.Wall, err = generator.TileParam(req.WallTile, tiles.Forest, set, "wallTile"); err != nil {
+		return nil, err
+	}
+	if opts.Floor, err = generator.TileParam(req.FloorTile, tiles.Sand, set, "floorTile"); err != nil {
+		return nil, err
+	}
+	grid, stats, err := caves.Generate(ctx, req.Width, req.Height, rand.New(rand.NewSource(req.SeedOr(generator.DefaultSeed))), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &generator.Result{Grid: fromGOL(grid), Frames: fromGOLFrames(stats.Frames), Stats: stats}, nil
+}
+
+// Samples sweeps 10 wall fills by 10 seeds.
+func (cavesGenerator) Samples(n int) []generator.Sample {
+	var samples []generator.Sample
+	for i := 0; i < 10; i++ { // caveFill 0.40 to 0.58
+		fill := 0.4 + float64(i)*0.02
+		for j := 0; j < 10 && len(samples) < n; j++ { // Seed 1 to 10
+			seed := int64(j + 1)
+			samples = append(samples, generator.Sample{
+				Name: fmt.Sprintf("caves_fill_%.2f_seed_%d", fill, seed),
+				Request: generator.Request{
+					GenerationMethod: "caves",
+					Seed:             &seed,
+					CaveFill:         &fill,
+				},
+			})
+		}
+	}
+	return samples
+}
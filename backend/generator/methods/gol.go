@@ -0,0 +1,251 @@
+package methods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"slices"
+	"sort"
+
+	"procedural-map-generation-toolkit/backend/generator"
+	"procedural-map-generation-toolkit/backend/gol"
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func init() {
+	generator.Register(golGenerator{})
+}
+
+type golGenerator struct{}
+
+func (golGenerator) Name() string { return "gol" }
+
+func (golGenerator) Description() string {
+	return "Game of Life style automata: Life-like B/S rules, Generations rules with decay states and cyclic CA"
+}
+
+func (golGenerator) Params() []generator.Param {
+	return append(generator.Common(),
+		generator.ParamIterations,
+		generator.Param{Name: "golRule", Type: "string", Description: "Preset, B/S or B/S/C notation, or \"cyclic\"",
+			Default: "conway", Suggestions: golRules()},
+		generator.Param{Name: "aliveTile", Type: "tile", Description: "Tile of live cells for Life-like rules", Default: int(gol.DefaultAlive)},
+		generator.Param{Name: "deadTile", Type: "tile", Description: "Tile of dead cells for Life-like rules", Default: int(gol.DefaultDead)},
+		generator.Param{Name: "golStates", Type: "json", Description: "Tiles from alive through decay to dead, or the cyclic states"},
+		generator.Param{Name: "golThreshold", Type: "int", Description: "Neighbors needed to advance a cyclic state", Default: 3}.WithRange(1, 100),
+		generator.Param{Name: "golDensity", Type: "float", Description: "Initial share of live cells", Default: 0.5}.WithRange(0, 1),
+		generator.Param{Name: "golPattern", Type: "string", Description: "Initial fill", Default: string(gol.PatternRandom),
+			Enum: []string{string(gol.PatternRandom), string(gol.PatternNoise), string(gol.PatternBorder)}},
+		generator.Param{Name: "golNoiseScale", Type: "float", Description: "Noise periods across the map for the noise fill", Default: 4.0}.WithRange(0, 100),
+		generator.ParamPrevGrid,
+		generator.ParamPaintedTiles,
+		generator.ParamNeighborhood,
+		generator.ParamNeighborhoodRadius,
+		generator.ParamNeighborhoodKernel,
+		generator.ParamSnapshotEvery,
+		generator.ParamSnapshotEncoding,
+	)
+}
+
+// golRules lists the rule presets and "cyclic"; other B/S strings are accepted too.
+func golRules() []string {
+	names := make([]string, 0, len(gol.Presets)+1)
+	for name := range gol.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, "cyclic")
+}
+
+// golModel builds the GOL rules from the request, together with the initial
+// fill and the tile types that may be painted. golRule selects a Life-like rule,
+// a Generations rule with decay states over golStates, or "cyclic".
+func golModel(req *generator.Request) ([]gol.Rule, gol.InitOptions, []tiles.TileType, error) {
+	fill := gol.InitOptions{Density: req.GOLDensity, NoiseScale: req.GOLNoiseScale}
+	pattern, err := gol.ParsePattern(req.GOLPattern)
+	if err != nil {
+		return nil, fill, nil, err
+	}
+	fill.Pattern = pattern
+	set := req.Tiles()
+	states := make([]tiles.TileType, len(req.GOLStates))
+	for i, v := range req.GOLStates {
+		if !set.Valid(v) {
+			return nil, fill, nil, fmt.Errorf("state tile %d out of range", v)
+		}
+		states[i] = tiles.TileType(v)
+	}
+
+	ruleName := req.GOLRule
+	if ruleName == "" {
+		ruleName = "conway"
+	}
+	switch {
+	case ruleName == "cyclic":
+		if len(states) == 0 {
+			// The inland tiles: grass overgrows sand, bushes grass, forest
+			// bushes, and fire clears forest
+			states = set.Inland()
+		}
+		threshold := req.GOLThreshold
+		if threshold == 0 {
+			threshold = 3
+		}
+		rules, err := gol.CyclicRules(states, threshold)
+		fill.States = states
+		return rules, fill, states, err
+	case gol.IsGenerations(ruleName):
+		g, err := gol.ParseGenerations(ruleName)
+		if err != nil {
+			return nil, fill, nil, err
+		}
+		if len(states) == 0 {
+			succession := succession(set)
+			if g.States > len(succession) {
+				return nil, fill, nil, fmt.Errorf("rule %s needs golStates for its %d states", g, g.States)
+			}
+			states = succession[:g.States]
+		}
+		rules, err := g.Rules(states)
+		if err != nil {
+			return nil, fill, nil, err
+		}
+		fill.Alive, fill.Dead = states[0], states[len(states)-1]
+		return rules, fill, states, nil
+	}
+
+	rule, err := gol.ParseLifeRule(ruleName)
+	if err != nil {
+		return nil, fill, nil, err
+	}
+	if fill.Alive, err = generator.TileParam(req.AliveTile, gol.DefaultAlive, set, "aliveTile"); err != nil {
+		return nil, fill, nil, err
+	}
+	if fill.Dead, err = generator.TileParam(req.DeadTile, gol.DefaultDead, set, "deadTile"); err != nil {
+		return nil, fill, nil, err
+	}
+	if fill.Alive == fill.Dead {
+		return nil, fill, nil, errors.New("alive and dead tiles must differ")
+	}
+	return rule.Rules(fill.Alive, fill.Dead), fill, []tiles.TileType{fill.Alive, fill.Dead}, nil
+}
+
+// succession returns the default Generations states of set, its tiles from
+// the highest id down. For tiles.Default this is gol.Succession.
+func succession(set *tiles.Set) []tiles.TileType {
+	if set == tiles.Default {
+		return gol.Succession
+	}
+	states := make([]tiles.TileType, set.Len())
+	for i := range states {
+		states[i] = tiles.TileType(set.Len() - 1 - i)
+	}
+	return states
+}
+
+func (golGenerator) Generate(ctx context.Context, req *generator.Request, progress generator.Progress) (*generator.Result, error) {
+	rules, fill, paintable, err := golModel(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var tileGrid [][]gol.Tile
+	if len(req.PrevGrid) > 0 {
+		prev, err := generator.GridFromInts(req.PrevGrid)
+		if err != nil {
+			return nil, err
+		}
+		tileGrid = toGOL(prev)
+	} else {
+		tileGrid, err = gol.InitializeGrid(req.Width, req.Height, rand.New(rand.NewSource(req.SeedOr(generator.DefaultSeed))), fill)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Paint overrides
+	for y := range req.PaintedTiles {
+		for x := range req.PaintedTiles[y] {
+			v := req.PaintedTiles[y][x]
+			if !req.Tiles().Valid(v) || y >= len(tileGrid) || x >= len(tileGrid[y]) {
+				continue
+			}
+			t := tiles.TileType(v)
+			if slices.Contains(paintable, t) {
+				tileGrid[y][x].State = t
+			} else {
+				log.Printf("Ignoring painted tile %d at %d,%d", v, x, y)
+			}
+		}
+	}
+	nbh, err := neighborhood.FromSpec(req.Neighborhood, req.NeighborhoodRadius, req.NeighborhoodKernel)
+	if err != nil {
+		return nil, err
+	}
+	opts := gol.Options{Neighborhood: nbh, SnapshotEvery: req.SnapshotEvery, Progress: progress.Step}
+	if progress.Frame != nil {
+		opts.Watch = func(grid [][]gol.Tile) { progress.Frame(fromGOL(grid)) }
+	}
+	next, snapshots, err := gol.ApplyCARules(ctx, tileGrid, rules, req.IterationsOr(0), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &generator.Result{Grid: fromGOL(next), Frames: fromGOLFrames(snapshots)}, nil
+}
+
+// Samples sweeps 10 initial densities by 10 seeds, smoothed by the 4-5 cave rule.
+func (golGenerator) Samples(n int) []generator.Sample {
+	var samples []generator.Sample
+	iterations := 5
+	for i := 0; i < 10; i++ { // golDensity 0.30 to 0.75
+		density := 0.3 + float64(i)*0.05
+		for j := 0; j < 10 && len(samples) < n; j++ { // Seed 1 to 10
+			seed := int64(j + 1)
+			samples = append(samples, generator.Sample{
+				Name: fmt.Sprintf("gol_density_%.2f_seed_%d", density, seed),
+				Request: generator.Request{
+					GenerationMethod: "gol",
+					Iterations:       &iterations,
+					Seed:             &seed,
+					GOLRule:          "cave45",
+					GOLDensity:       &density,
+				},
+			})
+		}
+	}
+	return samples
+}
+
+func toGOL(grid generator.Grid) [][]gol.Tile {
+	out := make([][]gol.Tile, len(grid))
+	for y, row := range grid {
+		out[y] = make([]gol.Tile, len(row))
+		for x, t := range row {
+			out[y][x] = gol.Tile{State: t}
+		}
+	}
+	return out
+}
+
+func fromGOL(grid [][]gol.Tile) generator.Grid {
+	out := make(generator.Grid, len(grid))
+	for y, row := range grid {
+		out[y] = make([]tiles.TileType, len(row))
+		for x, t := range row {
+			out[y][x] = t.State
+		}
+	}
+	return out
+}
+
+// fromGOLFrames converts recorded frames, freeing each one once converted.
+func fromGOLFrames(frames [][][]gol.Tile) []generator.Grid {
+	var out []generator.Grid
+	for i, f := range frames {
+		out = append(out, fromGOL(f))
+		frames[i] = nil
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package methods
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/generator"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func TestSchemas(t *testing.T) {
+	want := []string{"caves", "gol", "mlca", "noise", "wfc"}
+	if names := generator.Names(); !reflect.DeepEqual(names, want) {
+		t.Fatalf("Names() = %v, want %v", names, want)
+	}
+
+	// Every parameter a client is offered must decode into a request field
+	fields := map[string]bool{}
+	rt := reflect.TypeOf(generator.Request{})
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	for _, g := range generator.All() {
+		info := generator.Describe(g)
+		if info.Description == "" {
+			t.Errorf("%s has no description", g.Name())
+		}
+		var seen []string
+		for _, p := range info.Params {
+			if !fields[p.Name] {
+				t.Errorf("%s parameter %q is not a request field", g.Name(), p.Name)
+			}
+			if slices.Contains(seen, p.Name) {
+				t.Errorf("%s lists parameter %q twice", g.Name(), p.Name)
+			}
+			if p.Description == "" {
+				t.Errorf("%s parameter %q has no description", g.Name(), p.Name)
+			}
+			seen = append(seen, p.Name)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	const width, height = 12, 10
+	for _, g := range generator.All() {
+		t.Run(g.Name(), func(t *testing.T) {
+			seed, iterations := int64(3), 2
+			req := &generator.Request{GenerationMethod: g.Name(), Width: width, Height: height, Seed: &seed, Iterations: &iterations}
+			if generator.Unsupported(g, req) != nil {
+				req.Iterations = nil
+			}
+			res, err := g.Generate(context.Background(), req, generator.Progress{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Grid.Width() != width || res.Grid.Height() != height {
+				t.Fatalf("grid is %dx%d, want %dx%d", res.Grid.Width(), res.Grid.Height(), width, height)
+			}
+			set := req.Tiles()
+			for _, row := range res.Grid {
+				for _, c := range row {
+					if !set.Valid(int(c)) {
+						t.Fatalf("grid holds tile %d outside %s", c, set.Name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExplicitZeros(t *testing.T) {
+	zero, five, wall := 0, 5, int(tiles.DeepWater)
+	run := func(req generator.Request) generator.Grid {
+		t.Helper()
+		seed := int64(4)
+		req.Width, req.Height, req.Seed = 20, 16, &seed
+		g, _ := generator.Lookup(req.GenerationMethod)
+		res, err := g.Generate(context.Background(), &req, generator.Progress{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Grid
+	}
+
+	// Omitted iterations smooth caves five times, explicit zero not at all
+	unset := run(generator.Request{GenerationMethod: "caves", CaveRepair: "none"})
+	if !reflect.DeepEqual(unset, run(generator.Request{GenerationMethod: "caves", CaveRepair: "none", Iterations: &five})) {
+		t.Error("caves without iterations differ from five smoothing passes")
+	}
+	if reflect.DeepEqual(unset, run(generator.Request{GenerationMethod: "caves", CaveRepair: "none", Iterations: &zero})) {
+		t.Error("caves with zero iterations were smoothed")
+	}
+
+	// Tile 0 is honoured as a wall or live tile
+	for _, req := range []generator.Request{
+		{GenerationMethod: "caves", WallTile: &wall},
+		{GenerationMethod: "gol", AliveTile: &wall},
+	} {
+		grid := run(req)
+		if !slices.ContainsFunc(grid, func(row []tiles.TileType) bool { return slices.Contains(row, tiles.DeepWater) }) {
+			t.Errorf("%s ignored tile 0", req.GenerationMethod)
+		}
+	}
+}
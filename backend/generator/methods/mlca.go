@@ -0,0 +1,175 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"procedural-map-generation-toolkit/backend/generator"
+	"procedural-map-generation-toolkit/backend/mlca"
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func init() {
+	generator.Register(mlcaGenerator{})
+}
+
+type mlcaGenerator struct{}
+
+func (mlcaGenerator) Name() string { return "mlca" }
+
+func (mlcaGenerator) Description() string {
+	return "Multi-layered cellular automata: terrain rules grow coasts, beaches and vegetation from a random or painted grid"
+}
+
+func (mlcaGenerator) Params() []generator.Param {
+	return append(generator.Common(),
+		generator.ParamIterations,
+		generator.Param{Name: "randomnessFactor", Type: "float", Description: "Chance that a matching rule fires; 0 lets every rule fire"}.WithRange(0, 1),
+		generator.ParamPrevGrid,
+		generator.ParamPaintedTiles,
+		generator.Param{Name: "lockedTiles", Type: "mask", Description: "Painted cells rules may not overwrite"},
+		generator.Param{Name: "rules", Type: "json", Description: "Terrain rules; empty uses the default rule set of the terrain tile set"},
+		generator.Param{Name: "ruleSelection", Type: "string", Description: "How competing rules are resolved",
+			Default: string(mlca.SelectFirst), Enum: []string{string(mlca.SelectFirst), string(mlca.SelectWeighted), string(mlca.SelectPriority)}},
+		generator.ParamNeighborhood,
+		generator.ParamNeighborhoodRadius,
+		generator.ParamNeighborhoodKernel,
+		generator.Param{Name: "boundary", Type: "string", Description: "How neighbors beyond the edge are resolved",
+			Default: string(neighborhood.BoundaryFixed), Enum: boundaries()},
+		generator.Param{Name: "boundaryTile", Type: "tile", Description: "Tile beyond the edge for the fixed boundary", Default: int(tiles.DeepWater)},
+		generator.Param{Name: "stopOnConvergence", Type: "bool", Description: "Stop once the grid is stable or oscillating"},
+		generator.Param{Name: "minChangeFraction", Type: "float", Description: "Stop once fewer cells change per iteration"}.WithRange(0, 1),
+		generator.ParamSnapshotEvery,
+		generator.ParamSnapshotEncoding,
+	)
+}
+
+func boundaries() []string {
+	return []string{
+		string(neighborhood.BoundaryFixed), string(neighborhood.BoundaryClamp), string(neighborhood.BoundaryMirror),
+		string(neighborhood.BoundaryWrap), string(neighborhood.BoundaryIgnore),
+	}
+}
+
+func (mlcaGenerator) Generate(ctx context.Context, req *generator.Request, progress generator.Progress) (*generator.Result, error) {
+	// Convert painted and locked, ensuring correct dimensions for mlca.GenerateTiles
+	painted := make([][]tiles.TileType, req.Height)
+	var locked [][]bool
+	if len(req.LockedTiles) > 0 {
+		locked = make([][]bool, req.Height)
+	}
+	for y := 0; y < req.Height; y++ {
+		painted[y] = make([]tiles.TileType, req.Width)
+		if locked != nil {
+			locked[y] = make([]bool, req.Width)
+		}
+		for x := 0; x < req.Width; x++ {
+			// Default to -1 (not painted)
+			painted[y][x] = -1
+			if y < len(req.PaintedTiles) && x < len(req.PaintedTiles[y]) {
+				if req.PaintedTiles[y][x] != -1 {
+					painted[y][x] = tiles.TileType(req.PaintedTiles[y][x])
+				}
+			}
+			if locked != nil && y < len(req.LockedTiles) && x < len(req.LockedTiles[y]) {
+				locked[y][x] = req.LockedTiles[y][x]
+			}
+		}
+	}
+
+	nbh, err := neighborhood.FromSpec(req.Neighborhood, req.NeighborhoodRadius, req.NeighborhoodKernel)
+	if err != nil {
+		return nil, err
+	}
+	set := req.Tiles()
+	rules := req.Rules
+	if len(rules) == 0 {
+		if set != tiles.Default {
+			return nil, generator.ValidationError{{Field: "rules", Message: fmt.Sprintf("the default rules are written for tile set %q, tile set %q needs its own", tiles.Default.Name, set.Name)}}
+		}
+		// Default rules are written for 8 neighbors
+		rules = mlca.ScaleRules(mlca.CreateDefaultRules(), nbh.Size())
+	}
+	selection, err := mlca.ParseRuleSelection(req.RuleSelection)
+	if err != nil {
+		return nil, err
+	}
+	boundary, err := neighborhood.ParseBoundary(req.Boundary, neighborhood.BoundaryFixed)
+	if err != nil {
+		return nil, err
+	}
+	// Out-of-bounds neighbors default to deep water
+	boundaryTile, err := generator.TileParam(req.BoundaryTile, tiles.DeepWater, set, "boundaryTile")
+	if err != nil {
+		return nil, err
+	}
+	opts := mlca.Options{
+		Selection:         selection,
+		Tiles:             set,
+		Neighborhood:      nbh,
+		Boundary:          boundary,
+		BoundaryTile:      boundaryTile,
+		StopOnConvergence: req.StopOnConvergence,
+		MinChangeFraction: req.MinChangeFraction,
+		SnapshotEvery:     req.SnapshotEvery,
+		Locked:            locked,
+		Progress:          progress.Step,
+	}
+	if progress.Frame != nil {
+		opts.Watch = func(grid [][]mlca.Tile) { progress.Frame(fromMLCA(grid)) }
+	}
+	// Continue from a previous grid, e.g. the output of noise or WFC
+	if len(req.PrevGrid) > 0 {
+		prev, err := generator.GridFromInts(req.PrevGrid)
+		if err != nil {
+			return nil, err
+		}
+		opts.Initial = prev
+	}
+
+	tileGrid, stats, err := mlca.GenerateTiles(ctx, req.Width, req.Height, painted, req.IterationsOr(0), req.RandomnessFactor, rules,
+		rand.New(rand.NewSource(req.SeedOr(generator.DefaultSeed))), opts)
+	if err != nil {
+		return nil, err
+	}
+	res := &generator.Result{Grid: fromMLCA(tileGrid), Stats: stats}
+	for i, f := range stats.Frames {
+		res.Frames = append(res.Frames, fromMLCA(f))
+		// Free each recorded frame once converted, so at most one is held twice
+		stats.Frames[i] = nil
+	}
+	return res, nil
+}
+
+// Samples sweeps 10 iteration counts by 10 randomness factors.
+func (mlcaGenerator) Samples(n int) []generator.Sample {
+	var samples []generator.Sample
+	for i := 0; i < 10; i++ { // Iterations 1 to 10
+		iterations := i + 1
+		for j := 0; j < 10 && len(samples) < n; j++ { // RandomnessFactor 0.0 to 0.9
+			randomness := float64(j) * 0.1
+			samples = append(samples, generator.Sample{
+				Name: fmt.Sprintf("mlca_iter_%d_rand_%.2f", iterations, randomness),
+				Request: generator.Request{
+					GenerationMethod: "mlca",
+					Iterations:       &iterations,
+					RandomnessFactor: randomness,
+				},
+			})
+		}
+	}
+	return samples
+}
+
+func fromMLCA(grid [][]mlca.Tile) generator.Grid {
+	out := make(generator.Grid, len(grid))
+	for y, row := range grid {
+		out[y] = make([]tiles.TileType, len(row))
+		for x, t := range row {
+			out[y][x] = t.Color
+		}
+	}
+	return out
+}
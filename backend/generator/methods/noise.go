@@ -0,0 +1,61 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+
+	"procedural-map-generation-toolkit/backend/generator"
+	"procedural-map-generation-toolkit/backend/noise"
+)
+
+func init() {
+	generator.Register(noiseGenerator{})
+}
+
+type noiseGenerator struct{}
+
+func (noiseGenerator) Name() string { return "noise" }
+
+func (noiseGenerator) Description() string {
+	return "Perlin noise thresholded into the elevation bands of the tile set, from deep water to forest by default"
+}
+
+func (noiseGenerator) Params() []generator.Param {
+	return append(generator.Common(),
+		generator.Param{Name: "noiseScale", Type: "float", Description: "Noise periods across the map", Default: 0.5}.WithRange(0, 100),
+		generator.Param{Name: "noiseOctaves", Type: "int", Description: "Number of octaves", Default: 4},
+		generator.Param{Name: "noisePersistence", Type: "float", Description: "Amplitude factor per octave", Default: 0.9}.WithRange(0, 10),
+		generator.Param{Name: "noiseLacunarity", Type: "float", Description: "Frequency factor per octave", Default: 1.8}.WithRange(0, 10),
+	)
+}
+
+func (noiseGenerator) Generate(_ context.Context, req *generator.Request, _ generator.Progress) (*generator.Result, error) {
+	octaves := 4
+	if req.NoiseOctaves != nil {
+		octaves = *req.NoiseOctaves
+	}
+	ng := noise.NewNoiseGenerator(req.SeedOr(generator.DefaultSeed), req.NoiseScale, octaves, req.NoisePersistence, req.NoiseLacunarity)
+	ng.UseTiles(req.Tiles())
+	return &generator.Result{Grid: fromMLCA(ng.Generate(req.Width, req.Height))}, nil
+}
+
+// Samples sweeps 10 noise scales by 10 octave counts.
+func (noiseGenerator) Samples(n int) []generator.Sample {
+	var samples []generator.Sample
+	for i := 0; i < 10; i++ { // noiseScale from 0.2 to 2.0
+		scale := 0.2 + (float64(i) * 0.2)
+		for octaves := 1; octaves <= 10 && len(samples) < n; octaves++ {
+			samples = append(samples, generator.Sample{
+				Name: fmt.Sprintf("noise_scale_%.2f_oct_%d", scale, octaves),
+				Request: generator.Request{
+					GenerationMethod: "noise",
+					NoiseScale:       scale,
+					NoiseOctaves:     &octaves,
+					NoisePersistence: 0.9,
+					NoiseLacunarity:  1.8,
+				},
+			})
+		}
+	}
+	return samples
+}
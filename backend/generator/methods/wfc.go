@@ -0,0 +1,84 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+
+	"procedural-map-generation-toolkit/backend/generator"
+	"procedural-map-generation-toolkit/backend/tiles"
+	"procedural-map-generation-toolkit/backend/wfc"
+)
+
+func init() {
+	generator.Register(wfcGenerator{})
+}
+
+type wfcGenerator struct{}
+
+// WFCStats reports how much of a given grid WFC had to re-solve.
+type WFCStats struct {
+	RepairedCells int `json:"repairedCells"`
+}
+
+func (wfcGenerator) Name() string { return "wfc" }
+
+func (wfcGenerator) Description() string {
+	return "Wave function collapse with the adjacency rules of the tile set; repairs illegal adjacencies of a given grid"
+}
+
+func (wfcGenerator) Params() []generator.Param {
+	return append(generator.Common(),
+		generator.Param{Name: "wfcSeed", Type: "int", Description: "Seed, taking precedence over seed"},
+		generator.ParamPrevGrid,
+		generator.Param{Name: "wfcRepairRadius", Type: "int", Description: "Margin re-solved around illegal adjacencies of prevGrid", Default: 1}.WithRange(1, 64),
+	)
+}
+
+func (wfcGenerator) Generate(ctx context.Context, req *generator.Request, progress generator.Progress) (*generator.Result, error) {
+	gridObj := wfc.NewGrid(req.Width, req.Height, req.Tiles())
+	gridObj.Progress = progress.Step
+	if progress.Frame != nil {
+		gridObj.Watch = func(grid [][]tiles.TileType) { progress.Frame(grid) }
+	}
+
+	currentWFCSeed := req.SeedOr(generator.DefaultSeed)
+	if req.WFCSeed != nil {
+		currentWFCSeed = *req.WFCSeed
+	}
+
+	if len(req.PrevGrid) == 0 {
+		tilesOut, err := gridObj.Solve(ctx, 100, currentWFCSeed)
+		if err != nil {
+			return nil, err
+		}
+		return &generator.Result{Grid: tilesOut}, nil
+	}
+
+	// Repair mode: keep the given grid and re-solve illegal adjacencies
+	prev, err := generator.GridFromInts(req.PrevGrid)
+	if err != nil {
+		return nil, err
+	}
+	radius := req.WFCRepairRadius
+	if radius <= 0 {
+		radius = 1
+	}
+	tilesOut, repaired, err := gridObj.Repair(ctx, prev, radius, 100, currentWFCSeed)
+	if err != nil {
+		return nil, err
+	}
+	return &generator.Result{Grid: tilesOut, Stats: WFCStats{RepairedCells: repaired}}, nil
+}
+
+// Samples uses seeds 1 to n.
+func (wfcGenerator) Samples(n int) []generator.Sample {
+	samples := make([]generator.Sample, n)
+	for i := range samples {
+		seed := int64(i + 1)
+		samples[i] = generator.Sample{
+			Name:    fmt.Sprintf("wfc_seed_%d", seed),
+			Request: generator.Request{GenerationMethod: "wfc", WFCSeed: &seed},
+		}
+	}
+	return samples
+}
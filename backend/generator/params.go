@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// Param describes one request field a generator reads.
+type Param struct {
+	// Name is the JSON field name in Request
+	Name string `json:"name"`
+	// Type is one of "int", "float", "bool", "string", "tile", "grid", "mask" or "json"
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Default     any      `json:"default,omitempty"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+	// Enum lists the accepted values of string parameters
+	Enum []string `json:"enum,omitempty"`
+	// Suggestions lists common values of string parameters that accept others too
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// WithRange returns a copy of p limited to [min, max].
+func (p Param) WithRange(min, max float64) Param {
+	p.Min, p.Max = &min, &max
+	return p
+}
+
+// WithDefault returns a copy of p with the given default.
+func (p Param) WithDefault(v any) Param {
+	p.Default = v
+	return p
+}
+
+// Parameters shared by several generators
+var (
+	ParamWidth            = Param{Name: "width", Type: "int", Description: "Map width in tiles"}.WithRange(1, 4096)
+	ParamHeight           = Param{Name: "height", Type: "int", Description: "Map height in tiles"}.WithRange(1, 4096)
+	ParamSeed             = Param{Name: "seed", Type: "int", Description: "Random seed", Default: DefaultSeed}
+	ParamIterations       = Param{Name: "iterations", Type: "int", Description: "Number of automaton steps"}
+	ParamPrevGrid         = Param{Name: "prevGrid", Type: "grid", Description: "Grid to continue from"}
+	ParamPaintedTiles     = Param{Name: "paintedTiles", Type: "grid", Description: "Painted tiles, -1 for unpainted"}
+	ParamSnapshotEvery    = Param{Name: "snapshotEvery", Type: "int", Description: "Record a time-lapse frame every k iterations"}.WithRange(0, 1000)
+	ParamSnapshotEncoding = Param{Name: "snapshotEncoding", Type: "string", Description: "Time-lapse encoding", Default: "full",
+		Enum: []string{"full", "delta", "gif"}}
+	ParamNeighborhood = Param{Name: "neighborhood", Type: "string", Description: "Cellular automaton neighborhood",
+		Enum: []string{"moore", "vonneumann", "custom"}}
+	ParamNeighborhoodRadius = Param{Name: "neighborhoodRadius", Type: "int", Description: "Neighborhood radius", Default: 1}
+	ParamNeighborhoodKernel = Param{Name: "neighborhoodKernel", Type: "json", Description: "Custom neighborhood offsets with weights"}
+)
+
+// Common lists the parameters every generator reads.
+func Common() []Param {
+	return []Param{ParamWidth, ParamHeight, ParamSeed, ParamTileSet()}
+}
+
+// ParamTileSet describes the tileSet field with the sets registered so far.
+func ParamTileSet() Param {
+	return Param{Name: "tileSet", Type: "string", Description: "Tile set the grid values refer to",
+		Default: tiles.Default.Name, Enum: tiles.Names()}
+}
+
+// SetFields returns the JSON names of the request fields that hold a value,
+// treating empty slices like unset ones.
+func SetFields(req *Request) []string {
+	var names []string
+	v := reflect.ValueOf(req).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.IsZero() || (f.Kind() == reflect.Slice && f.Len() == 0) {
+			continue
+		}
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	return names
+}
+
+// Unsupported returns the fields set in req that g does not read.
+func Unsupported(g Generator, req *Request) []string {
+	known := map[string]bool{"generationMethod": true}
+	for _, p := range g.Params() {
+		known[p.Name] = true
+	}
+	var unknown []string
+	for _, name := range SetFields(req) {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
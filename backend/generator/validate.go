@@ -0,0 +1,361 @@
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/render"
+	"procedural-map-generation-toolkit/backend/tiles"
+	"procedural-map-generation-toolkit/backend/timelapse"
+)
+
+// Limits bounds the work a single request may ask for.
+type Limits struct {
+	MaxWidth, MaxHeight int
+	// MaxCells bounds width*height
+	MaxCells      int
+	MaxIterations int
+	MaxOctaves    int
+	// MaxRadius bounds the neighborhood radius and custom kernel offsets
+	MaxRadius int
+	// MaxFrames bounds the number of recorded time-lapse frames
+	MaxFrames int
+	// MaxFrameCells bounds frames*width*height, the cells held by a time-lapse
+	MaxFrameCells int
+	// MaxRules bounds the number of MLCA rules in a request
+	MaxRules int
+	// MaxImagePixels bounds the size of rendered images, see ImagePixels
+	MaxImagePixels int
+	// MaxExamples bounds the number of example grids rules are learned from;
+	// together they must not exceed MaxCells
+	MaxExamples int
+}
+
+// DefaultLimits allow maps up to 1024x1024.
+var DefaultLimits = Limits{
+	MaxWidth:      1024,
+	MaxHeight:     1024,
+	MaxCells:      1 << 20,
+	MaxIterations: 1000,
+	MaxOctaves:    16,
+	MaxRadius:     8,
+	MaxFrames:     200,
+	MaxFrameCells: 16 << 20,
+	MaxRules:      256,
+	// A 1024x1024 map at the default 8 pixels per tile
+	MaxImagePixels: 64 << 20,
+	MaxExamples:    64,
+}
+
+// ImagePixels returns the number of pixels of a width x height grid drawn
+// with cellSize pixels per tile; zero is render.DefaultCellSize.
+func ImagePixels(width, height, cellSize int) int {
+	if cellSize <= 0 {
+		cellSize = render.DefaultCellSize
+	}
+	return width * height * cellSize * cellSize
+}
+
+// Apply returns params with the limits filled in as maxima.
+func (l Limits) Apply(params []Param) []Param {
+	out := make([]Param, len(params))
+	for i, p := range params {
+		switch p.Name {
+		case "width":
+			p = p.WithRange(1, float64(l.MaxWidth))
+		case "height":
+			p = p.WithRange(1, float64(l.MaxHeight))
+		case "iterations":
+			p = p.WithRange(0, float64(l.MaxIterations))
+		case "noiseOctaves":
+			p = p.WithRange(1, float64(l.MaxOctaves))
+		case "neighborhoodRadius":
+			p = p.WithRange(1, float64(l.MaxRadius))
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// FieldError is a problem with one request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found in a request.
+type ValidationError []FieldError
+
+func (v ValidationError) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Field + ": " + e.Message
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+// Prefix returns the errors with prefix prepended to the field names, e.g.
+// for the stages of a pipeline.
+func (v ValidationError) Prefix(prefix string) ValidationError {
+	out := make(ValidationError, len(v))
+	for i, e := range v {
+		out[i] = FieldError{Field: prefix + e.Field, Message: e.Message}
+	}
+	return out
+}
+
+type validator struct {
+	errs ValidationError
+	// set is the request's tile set, checked by the tileSet parameter
+	set *tiles.Set
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks req against the limits and the parameter schema of g. It
+// returns nil or a ValidationError. Zero values of optional fields are left
+// to the generator's defaults.
+func Validate(g Generator, req *Request, limits Limits) error {
+	v := &validator{set: req.Tiles()}
+
+	if req.Width < 1 || req.Width > limits.MaxWidth {
+		v.add("width", "must be between 1 and %d", limits.MaxWidth)
+	}
+	if req.Height < 1 || req.Height > limits.MaxHeight {
+		v.add("height", "must be between 1 and %d", limits.MaxHeight)
+	}
+	if req.Width > 0 && req.Height > 0 && req.Width*req.Height > limits.MaxCells {
+		v.add("width", "width*height must not exceed %d cells", limits.MaxCells)
+	}
+	if req.Iterations != nil && (*req.Iterations < 0 || *req.Iterations > limits.MaxIterations) {
+		v.add("iterations", "must be between 0 and %d", limits.MaxIterations)
+	}
+	if req.NoiseOctaves != nil && (*req.NoiseOctaves < 1 || *req.NoiseOctaves > limits.MaxOctaves) {
+		v.add("noiseOctaves", "must be between 1 and %d", limits.MaxOctaves)
+	}
+	if req.SnapshotEvery > 0 {
+		frames := req.IterationsOr(0)/req.SnapshotEvery + 2
+		if frames > limits.MaxFrames {
+			v.add("snapshotEvery", "would record more than %d frames", limits.MaxFrames)
+		} else if req.Width > 0 && req.Height > 0 && frames*req.Width*req.Height > limits.MaxFrameCells {
+			v.add("snapshotEvery", "frames*width*height must not exceed %d cells", limits.MaxFrameCells)
+		} else if req.SnapshotEncoding == timelapse.EncodingGIF && req.Width > 0 && req.Height > 0 &&
+			frames*ImagePixels(req.Width, req.Height, timelapse.GIFCellSize) > limits.MaxImagePixels {
+			// image/gif holds every rendered frame until it encodes them
+			v.add("snapshotEvery", "the gif frames must not exceed %d pixels together", limits.MaxImagePixels)
+		}
+	}
+	if req.Image != "" && !slices.Contains(render.Formats, req.Image) {
+		v.add("image", "must be one of %s", strings.Join(render.Formats, ", "))
+	}
+	if req.ImageCellSize < 0 || req.ImageCellSize > render.MaxCellSize {
+		v.add("imageCellSize", "must be between 1 and %d", render.MaxCellSize)
+	} else if req.Image != "" && req.Width > 0 && req.Height > 0 &&
+		ImagePixels(req.Width, req.Height, req.ImageCellSize) > limits.MaxImagePixels {
+		v.add("imageCellSize", "the image must not exceed %d pixels", limits.MaxImagePixels)
+	}
+
+	params := limits.Apply(g.Params())
+	for _, p := range params {
+		v.checkParam(p, req)
+	}
+
+	// Fields whose structure the schema does not describe
+	if req.Width > 0 && req.Height > 0 {
+		if len(req.PrevGrid) > 0 {
+			v.checkGrid("prevGrid", req.PrevGrid, req.Width, req.Height, 0, true)
+		}
+		if len(req.PaintedTiles) > 0 {
+			v.checkGrid("paintedTiles", req.PaintedTiles, req.Width, req.Height, -1, false)
+		}
+		if len(req.LockedTiles) > req.Height {
+			v.add("lockedTiles", "has more than %d rows", req.Height)
+		}
+		for y, row := range req.LockedTiles {
+			if len(row) > req.Width {
+				v.add(fmt.Sprintf("lockedTiles[%d]", y), "has more than %d cells", req.Width)
+				break
+			}
+		}
+	}
+	for i, t := range req.GOLStates {
+		v.checkTile(fmt.Sprintf("golStates[%d]", i), t)
+	}
+	if len(req.Rules) > limits.MaxRules {
+		v.add("rules", "must not hold more than %d rules", limits.MaxRules)
+	}
+	for i, r := range req.Rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		v.checkTile(field+".source", int(r.SourceColor))
+		v.checkTile(field+".target", int(r.TargetColor))
+		for j, t := range r.NeighborTypes {
+			v.checkTile(fmt.Sprintf("%s.neighborTypes[%d]", field, j), int(t))
+		}
+		if r.Probability != nil && (*r.Probability < 0 || *r.Probability > 1) {
+			v.add(field+".probability", "must be between 0 and 1")
+		}
+	}
+	v.checkNeighborhood(req.NeighborhoodRadius, req.NeighborhoodKernel, limits)
+	return v.result()
+}
+
+// ValidateLearn checks the input of MLCA rule learning against the same
+// limits as Validate: the example grids, which must be rectangular grids of
+// set, the number of rules and the neighborhood.
+func ValidateLearn(examples [][][]int, maxRules, radius int, kernel []neighborhood.Offset, set *tiles.Set, limits Limits) error {
+	v := &validator{set: set}
+	if len(examples) == 0 {
+		v.add("examples", "must not be empty")
+	} else if len(examples) > limits.MaxExamples {
+		v.add("examples", "must not hold more than %d grids", limits.MaxExamples)
+	}
+	cells := 0
+	for i, grid := range examples {
+		field := fmt.Sprintf("examples[%d]", i)
+		if len(grid) == 0 || len(grid[0]) == 0 {
+			v.add(field, "must not be empty")
+			continue
+		}
+		width, height := len(grid[0]), len(grid)
+		if width > limits.MaxWidth || height > limits.MaxHeight {
+			v.add(field, "must be at most %d x %d", limits.MaxWidth, limits.MaxHeight)
+			continue
+		}
+		cells += width * height
+		v.checkGrid(field, grid, width, height, 0, true)
+	}
+	if cells > limits.MaxCells {
+		v.add("examples", "must not hold more than %d cells in total", limits.MaxCells)
+	}
+	if maxRules < 0 || maxRules > limits.MaxRules {
+		v.add("maxRules", "must be between 1 and %d", limits.MaxRules)
+	}
+	v.checkNeighborhood(radius, kernel, limits)
+	return v.result()
+}
+
+// checkNeighborhood checks the radius and custom kernel of a neighborhood.
+func (v *validator) checkNeighborhood(radius int, kernel []neighborhood.Offset, limits Limits) {
+	if radius < 0 || radius > limits.MaxRadius {
+		v.add("neighborhoodRadius", "must be between 1 and %d", limits.MaxRadius)
+	}
+	maxKernel := (2*limits.MaxRadius + 1) * (2*limits.MaxRadius + 1)
+	if len(kernel) > maxKernel {
+		v.add("neighborhoodKernel", "must not hold more than %d offsets", maxKernel)
+	}
+	for i, o := range kernel {
+		if abs(o.DX) > limits.MaxRadius || abs(o.DY) > limits.MaxRadius {
+			v.add(fmt.Sprintf("neighborhoodKernel[%d]", i), "offset must be within %d cells", limits.MaxRadius)
+		}
+		if o.Weight < 0 || o.Weight > 100 {
+			v.add(fmt.Sprintf("neighborhoodKernel[%d].weight", i), "must be between 0 and 100")
+		}
+	}
+}
+
+// result returns nil or the collected errors.
+func (v *validator) result() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// checkParam checks the ranges, enums and tile types declared in the schema.
+func (v *validator) checkParam(p Param, req *Request) {
+	f, ok := fieldByJSON(req, p.Name)
+	if !ok {
+		return
+	}
+	if f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			return
+		}
+		f = f.Elem()
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int64:
+		n := f.Int()
+		if p.Type == "tile" {
+			v.checkTile(p.Name, int(n))
+		} else if n != 0 {
+			v.checkRange(p, float64(n))
+		}
+	case reflect.Float64:
+		if x := f.Float(); x != 0 {
+			v.checkRange(p, x)
+		}
+	case reflect.String:
+		if s := f.String(); s != "" && len(p.Enum) > 0 && !slices.Contains(p.Enum, s) {
+			v.add(p.Name, "must be one of %s", strings.Join(p.Enum, ", "))
+		}
+	}
+}
+
+func (v *validator) checkRange(p Param, x float64) {
+	if p.Name == "width" || p.Name == "height" || p.Name == "iterations" || p.Name == "noiseOctaves" || p.Name == "neighborhoodRadius" {
+		// Checked against the limits above
+		return
+	}
+	if (p.Min != nil && x < *p.Min) || (p.Max != nil && x > *p.Max) {
+		v.add(p.Name, "must be between %g and %g", deref(p.Min), deref(p.Max))
+	}
+}
+
+func (v *validator) checkTile(field string, t int) {
+	if !v.set.Valid(t) {
+		v.add(field, "tile type must be between 0 and %d", v.set.Len()-1)
+	}
+}
+
+// checkGrid checks the dimensions of a grid and that its values are tile
+// types or at least min. exact requires the full width x height.
+func (v *validator) checkGrid(field string, grid [][]int, width, height, min int, exact bool) {
+	if len(grid) > height || (exact && len(grid) != height) {
+		v.add(field, "must have %d rows, got %d", height, len(grid))
+		return
+	}
+	for y, row := range grid {
+		if len(row) > width || (exact && len(row) != width) {
+			v.add(fmt.Sprintf("%s[%d]", field, y), "must have %d cells, got %d", width, len(row))
+			return
+		}
+		for x, t := range row {
+			if t < min || (t >= 0 && !v.set.Valid(t)) {
+				v.add(fmt.Sprintf("%s[%d][%d]", field, y, x), "tile type must be between %d and %d", min, v.set.Len()-1)
+				return
+			}
+		}
+	}
+}
+
+// fieldByJSON finds the Request field with the given JSON name.
+func fieldByJSON(req *Request, name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(req).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func deref(p *float64) float64 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/mlca"
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// stub is a generator with a fixed schema; Validate never runs it.
+type stub []Param
+
+func (stub) Name() string        { return "stub" }
+func (stub) Description() string { return "test generator" }
+func (s stub) Params() []Param   { return s }
+func (stub) Generate(context.Context, *Request, Progress) (*Result, error) {
+	return nil, errors.New("not implemented")
+}
+
+var testLimits = Limits{
+	MaxWidth:       64,
+	MaxHeight:      32,
+	MaxCells:       1024,
+	MaxIterations:  100,
+	MaxOctaves:     4,
+	MaxRadius:      2,
+	MaxFrames:      10,
+	MaxFrameCells:  4096,
+	MaxRules:       2,
+	MaxImagePixels: 16384,
+	MaxExamples:    2,
+}
+
+// intp returns a pointer to n.
+func intp(n int) *int { return &n }
+
+// fields returns the field names of a ValidationError, nil for no error.
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error %v is not a ValidationError", err)
+	}
+	names := make([]string, len(verr))
+	for i, e := range verr {
+		names[i] = e.Field
+	}
+	return names
+}
+
+func TestValidate(t *testing.T) {
+	g := stub(append(Common(),
+		ParamIterations, ParamPrevGrid, ParamPaintedTiles, ParamSnapshotEvery, ParamSnapshotEncoding,
+		Param{Name: "wallTile", Type: "tile", Description: "Wall tile"},
+		Param{Name: "caveFill", Type: "float", Description: "Wall share"}.WithRange(0, 1),
+	))
+	p := func(v float64) *float64 { return &v }
+	tile := func(v int) *int { return &v }
+	tests := []struct {
+		name string
+		req  Request
+		want []string
+	}{
+		{"valid", Request{Width: 16, Height: 16, Iterations: intp(10)}, nil},
+		{"width", Request{Width: 65, Height: 1}, []string{"width"}},
+		{"zero size", Request{}, []string{"width", "height"}},
+		{"cells", Request{Width: 64, Height: 32}, []string{"width"}},
+		{"iterations", Request{Width: 4, Height: 4, Iterations: intp(101)}, []string{"iterations"}},
+		{"octaves", Request{Width: 4, Height: 4, NoiseOctaves: intp(4)}, nil},
+		{"zero octaves", Request{Width: 4, Height: 4, NoiseOctaves: intp(0)}, []string{"noiseOctaves"}},
+		{"too many octaves", Request{Width: 4, Height: 4, NoiseOctaves: intp(5)}, []string{"noiseOctaves"}},
+		{"frames", Request{Width: 4, Height: 4, Iterations: intp(100), SnapshotEvery: 5}, []string{"snapshotEvery"}},
+		// 10 frames of 32x16 cells
+		{"frame cells", Request{Width: 32, Height: 16, Iterations: intp(80), SnapshotEvery: 10}, []string{"snapshotEvery"}},
+		{"frame cells within", Request{Width: 32, Height: 16, Iterations: intp(30), SnapshotEvery: 10}, nil},
+		{"encoding", Request{Width: 4, Height: 4, SnapshotEncoding: "mp4"}, []string{"snapshotEncoding"}},
+		// Four 8x8 frames at 8 pixels per tile fill the 16384 pixels
+		{"gif pixels within", Request{Width: 8, Height: 8, Iterations: intp(20), SnapshotEvery: 10, SnapshotEncoding: "gif"}, nil},
+		{"gif pixels", Request{Width: 8, Height: 8, Iterations: intp(30), SnapshotEvery: 10, SnapshotEncoding: "gif"}, []string{"snapshotEvery"}},
+		{"image format", Request{Width: 4, Height: 4, Image: "jpeg"}, []string{"image"}},
+		{"image cell size", Request{Width: 4, Height: 4, Image: "png", ImageCellSize: 65}, []string{"imageCellSize"}},
+		// 32x16 tiles at 8 pixels is 32768 pixels
+		{"image pixels", Request{Width: 32, Height: 16, Image: "png"}, []string{"imageCellSize"}},
+		{"image pixels without image", Request{Width: 32, Height: 16}, nil},
+		{"image pixels within", Request{Width: 32, Height: 16, Image: "png", ImageCellSize: 5}, nil},
+		{"tile", Request{Width: 4, Height: 4, WallTile: tile(8)}, []string{"wallTile"}},
+		{"range", Request{Width: 4, Height: 4, CaveFill: p(1.5)}, []string{"caveFill"}},
+		{"fill 0", Request{Width: 4, Height: 4, CaveFill: p(0)}, nil},
+		{"tile set", Request{Width: 4, Height: 4, TileSet: "missing"}, []string{"tileSet"}},
+		{"prev grid rows", Request{Width: 2, Height: 2, PrevGrid: [][]int{{0, 1}}}, []string{"prevGrid"}},
+		{"prev grid tile", Request{Width: 2, Height: 1, PrevGrid: [][]int{{0, -1}}}, []string{"prevGrid[0][1]"}},
+		{"painted unpainted", Request{Width: 2, Height: 1, PaintedTiles: [][]int{{-1, 7}}}, nil},
+		{"painted tile", Request{Width: 2, Height: 1, PaintedTiles: [][]int{{-1, 8}}}, []string{"paintedTiles[0][1]"}},
+		{"painted below -1", Request{Width: 2, Height: 1, PaintedTiles: [][]int{{-2}}}, []string{"paintedTiles[0][0]"}},
+		{"locked rows", Request{Width: 1, Height: 1, LockedTiles: [][]bool{{true}, {true}}}, []string{"lockedTiles"}},
+		{"rules", Request{Width: 4, Height: 4, Rules: make([]mlca.TerrainRule, 3)}, []string{"rules"}},
+		{"rule tiles", Request{Width: 4, Height: 4, Rules: []mlca.TerrainRule{
+			{SourceColor: 8, TargetColor: 0, NeighborTypes: []tiles.TileType{9}, Probability: p(2)},
+		}}, []string{"rules[0].source", "rules[0].neighborTypes[0]", "rules[0].probability"}},
+		{"radius", Request{Width: 4, Height: 4, NeighborhoodRadius: 3}, []string{"neighborhoodRadius"}},
+		{"kernel", Request{Width: 4, Height: 4, NeighborhoodKernel: []neighborhood.Offset{{DX: 3, Weight: 1}, {DY: 1, Weight: -1}}},
+			[]string{"neighborhoodKernel[0]", "neighborhoodKernel[1].weight"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fields(t, Validate(g, &tt.req, testLimits)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLearn(t *testing.T) {
+	grid := func(width, height, tile int) [][]int {
+		g := make([][]int, height)
+		for y := range g {
+			g[y] = make([]int, width)
+			for x := range g[y] {
+				g[y][x] = tile
+			}
+		}
+		return g
+	}
+	tests := []struct {
+		name     string
+		examples [][][]int
+		maxRules int
+		radius   int
+		want     []string
+	}{
+		{"valid", [][][]int{grid(8, 8, 1), grid(4, 2, 7)}, 2, 1, nil},
+		{"no examples", nil, 2, 1, []string{"examples"}},
+		{"too many examples", [][][]int{grid(1, 1, 0), grid(1, 1, 0), grid(1, 1, 0)}, 2, 1, []string{"examples"}},
+		{"empty grid", [][][]int{{}}, 2, 1, []string{"examples[0]"}},
+		{"too wide", [][][]int{grid(65, 1, 0)}, 2, 1, []string{"examples[0]"}},
+		{"ragged", [][][]int{{{0, 1}, {0}}}, 2, 1, []string{"examples[0][1]"}},
+		{"tile", [][][]int{grid(2, 2, 8)}, 2, 1, []string{"examples[0][0][0]"}},
+		{"total cells", [][][]int{grid(32, 32, 0), grid(1, 1, 0)}, 2, 1, []string{"examples"}},
+		{"max rules", [][][]int{grid(2, 2, 0)}, 3, 1, []string{"maxRules"}},
+		{"radius", [][][]int{grid(2, 2, 0)}, 2, 3, []string{"neighborhoodRadius"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLearn(tt.examples, tt.maxRules, tt.radius, nil, tiles.Default, testLimits)
+			if got := fields(t, err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateLearn() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
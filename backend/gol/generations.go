@@ -0,0 +1,103 @@
+package gol
+
+import (
+	"fmt"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// Generations is a Life-like rule with decay: a live cell that does not
+// survive passes through States-2 decay states before it is dead, and only
+// dead cells can be born.
+type Generations struct {
+	LifeRule
+	States int
+}
+
+// Succession is the default order of tile types for Generations states:
+// vegetation decays Forest → Bushes → Grass → Sand and so on.
+var Succession = []tiles.TileType{
+	tiles.Forest, tiles.Bushes, tiles.Grass, tiles.Sand,
+	tiles.WetSand, tiles.CoastalWater, tiles.Water, tiles.DeepWater,
+}
+
+// ParseGenerations parses a preset name or B/S/C notation such as
+// "B2/S345/C4", where C counts all states including alive and dead. Without a
+// C part the rule has two states and behaves like a LifeRule.
+func ParseGenerations(s string) (Generations, error) {
+	r, states, err := parseNotation(s)
+	if states == 0 {
+		states = 2
+	}
+	return Generations{LifeRule: r, States: states}, err
+}
+
+// IsGenerations reports whether s is a preset or rule with decay states.
+func IsGenerations(s string) bool {
+	_, states, err := parseNotation(s)
+	return err == nil && states > 2
+}
+
+// String returns the rule in B/S/C notation.
+func (g Generations) String() string {
+	return fmt.Sprintf("%s/C%d", g.LifeRule, g.States)
+}
+
+// Rules compiles the rule for the given states, ordered from alive through
+// the decay states to dead. Cells of any other type count as dead.
+func (g Generations) Rules(states []tiles.TileType) ([]Rule, error) {
+	if len(states) != g.States {
+		return nil, fmt.Errorf("rule %s needs %d states, got %d", g, g.States, len(states))
+	}
+	if err := distinct(states); err != nil {
+		return nil, err
+	}
+	live, deadState := states[0], states[len(states)-1]
+	var rules []Rule
+	for _, span := range spans(g.Survive) {
+		rules = append(rules, Rule{CurrentState: live, NeighborState: live, MinCount: span[0], MaxCount: span[1], TargetState: live})
+	}
+	// Live cells that do not survive start to decay
+	rules = append(rules, Rule{CurrentState: live, NeighborState: live, MinCount: 0, MaxCount: -1, TargetState: states[1]})
+	// Decay states advance unconditionally and cannot give birth
+	for i := 1; i < len(states)-1; i++ {
+		rules = append(rules, Rule{CurrentState: states[i], NeighborState: live, MinCount: 0, MaxCount: -1, TargetState: states[i+1]})
+	}
+	for _, span := range spans(g.Birth) {
+		rules = append(rules, Rule{CurrentState: anyState, NeighborState: live, MinCount: span[0], MaxCount: span[1], TargetState: live})
+	}
+	rules = append(rules, Rule{CurrentState: anyState, NeighborState: live, MinCount: 0, MaxCount: -1, TargetState: deadState})
+	return rules, nil
+}
+
+// CyclicRules returns a cyclic CA over states: a cell in states[k] is consumed
+// by states[k+1] (wrapping around) once at least threshold neighbors are in
+// that state. Cells of other tile types never change.
+func CyclicRules(states []tiles.TileType, threshold int) ([]Rule, error) {
+	if len(states) < 2 {
+		return nil, fmt.Errorf("cyclic CA needs at least 2 states, got %d", len(states))
+	}
+	if threshold <= 0 {
+		return nil, fmt.Errorf("cyclic threshold must be positive, got %d", threshold)
+	}
+	if err := distinct(states); err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, len(states))
+	for k, s := range states {
+		next := states[(k+1)%len(states)]
+		rules[k] = Rule{CurrentState: s, NeighborState: next, MinCount: threshold, MaxCount: -1, TargetState: next}
+	}
+	return rules, nil
+}
+
+func distinct(states []tiles.TileType) error {
+	seen := make(map[tiles.TileType]bool, len(states))
+	for _, s := range states {
+		if seen[s] {
+			return fmt.Errorf("state %d appears twice", s)
+		}
+		seen[s] = true
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package gol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func TestParseGenerations(t *testing.T) {
+	tests := []struct {
+		in     string
+		states int
+		// str is the String form; empty expects an error
+		str         string
+		generations bool
+	}{
+		{"B2/S/C3", 3, "B2/S/C3", true},
+		{"brianbrain", 3, "B2/S/C3", true},
+		{"starwars", 4, "B2/S345/C4", true},
+		{"c4/s2345/b3", 4, "B3/S2345/C4", true},
+		{"B3/S23", 2, "B3/S23/C2", false},
+		{"B3/S23/C1", 0, "", false},
+		{"B3/S23/Cx", 0, "", false},
+		{"B3/C3", 0, "", false},
+	}
+	for _, tt := range tests {
+		g, err := ParseGenerations(tt.in)
+		if IsGenerations(tt.in) != tt.generations {
+			t.Errorf("IsGenerations(%q) = %t, want %t", tt.in, !tt.generations, tt.generations)
+		}
+		if tt.str == "" {
+			if err == nil {
+				t.Errorf("ParseGenerations(%q) = %s, want an error", tt.in, g)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseGenerations(%q): %v", tt.in, err)
+			continue
+		}
+		if g.States != tt.states || g.String() != tt.str {
+			t.Errorf("ParseGenerations(%q) = %s, want %s", tt.in, g, tt.str)
+		}
+	}
+}
+
+func TestGenerationsDecay(t *testing.T) {
+	g, err := ParseGenerations("brianbrain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := []tiles.TileType{tiles.Forest, tiles.Grass, tiles.Sand}
+	rules, err := g.Rules(states)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid := [][]Tile{{{tiles.Sand}, {tiles.Forest}, {tiles.Sand}, {tiles.Forest}, {tiles.Sand}}}
+	// Live cells always decay, and the dead cell between two of them is born
+	want := [][][]tiles.TileType{
+		{{tiles.Sand, tiles.Grass, tiles.Forest, tiles.Grass, tiles.Sand}},
+		{{tiles.Sand, tiles.Sand, tiles.Grass, tiles.Sand, tiles.Sand}},
+		{{tiles.Sand, tiles.Sand, tiles.Sand, tiles.Sand, tiles.Sand}},
+	}
+	for step, w := range want {
+		grid, _, err = ApplyCARules(context.Background(), grid, rules, 1, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][]tiles.TileType
+		for _, row := range grid {
+			var r []tiles.TileType
+			for _, c := range row {
+				r = append(r, c.State)
+			}
+			got = append(got, r)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("step %d: %v, want %v", step+1, got, w)
+		}
+	}
+
+	for _, states := range [][]tiles.TileType{
+		{tiles.Forest, tiles.Sand},
+		{tiles.Forest, tiles.Grass, tiles.Forest},
+	} {
+		if _, err := g.Rules(states); err == nil {
+			t.Errorf("Rules(%v) succeeded, want an error", states)
+		}
+	}
+}
+
+func TestCyclicRules(t *testing.T) {
+	states := []tiles.TileType{tiles.Water, tiles.Sand, tiles.Grass}
+	rules, err := CyclicRules(states, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each state is consumed by the next one, wrapping around; other tiles
+	// never change
+	grid := [][]Tile{{{tiles.Water}, {tiles.Sand}, {tiles.Grass}, {tiles.Forest}}}
+	got, _, err := ApplyCARules(context.Background(), grid, rules, 1, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]Tile{{{tiles.Sand}, {tiles.Grass}, {tiles.Grass}, {tiles.Forest}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("one step gave %v, want %v", TilesToIntGrid(got), TilesToIntGrid(want))
+	}
+
+	tests := []struct {
+		states    []tiles.TileType
+		threshold int
+	}{
+		{[]tiles.TileType{tiles.Water}, 1},
+		{states, 0},
+		{[]tiles.TileType{tiles.Water, tiles.Water}, 1},
+	}
+	for _, tt := range tests {
+		if _, err := CyclicRules(tt.states, tt.threshold); err == nil {
+			t.Errorf("CyclicRules(%v, %d) succeeded, want an error", tt.states, tt.threshold)
+		}
+	}
+}
@@ -1,15 +1,25 @@
 package gol
 
 import (
+	"context"
 	"errors"
 	"math/rand"
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/parallel"
 	"procedural-map-generation-toolkit/backend/tiles"
 )
 
+// lifeProbability is the default initial share of live cells
 const lifeProbability = 0.5
 
-const alive = tiles.Bushes
-const dead = tiles.Sand
+// Default tile types for live and dead cells
+const (
+	DefaultAlive = tiles.Bushes
+	DefaultDead  = tiles.Sand
+)
+
+const alive = DefaultAlive
+const dead = DefaultDead
 const anyState = -1
 
 type Tile struct {
@@ -31,56 +41,93 @@ func (r Rule) Applies(t Tile, neighbors []Tile) bool {
 	return count >= r.MinCount && (r.MaxCount < 0 || count <= r.MaxCount)
 }
 
-func InitializeGrid(width, height int) [][]Tile {
-	grid := make([][]Tile, height)
-	for y := 0; y < height; y++ {
-		grid[y] = make([]Tile, width)
-		for x := 0; x < width; x++ {
-			if rand.Float64() < lifeProbability {
-
-				// Cell Alive
-				grid[y][x] = Tile{State: alive}
-			} else {
-
-				// Cell Dead
-				grid[y][x] = Tile{State: dead}
-			}
-		}
-	}
-	return grid
+// Options holds optional settings for ApplyCARules.
+type Options struct {
+	// Neighborhood defaults to the 8-cell Moore neighborhood when nil.
+	// Rule counts are taken against its total weight.
+	Neighborhood neighborhood.Neighborhood
+	// SnapshotEvery records the grid every k iterations, together with the
+	// initial and final grid. Zero disables recording.
+	SnapshotEvery int
+	// Workers is the number of goroutines stepping row bands; zero uses all CPUs.
+	Workers int
+	// Boundary resolves neighbors outside the grid; empty drops them like
+	// BoundaryIgnore. BoundaryFixed counts them as BoundaryTile.
+	Boundary     neighborhood.Boundary
+	BoundaryTile tiles.TileType
+	// Progress, when set, is called after every iteration with the number of
+	// iterations run and requested.
+	Progress func(done, total int)
+	// Watch, when set, receives the grid after every iteration. The grid is
+	// reused by the next iteration and must not be kept.
+	Watch func(grid [][]Tile)
 }
 
-func ApplyCARules(grid [][]Tile, rules []Rule, iterations int) ([][]Tile, error) {
+// ApplyCARules runs the rules for the given number of iterations and returns
+// the final grid and any recorded snapshots. It stops with ctx.Err() when ctx
+// is canceled.
+func ApplyCARules(ctx context.Context, grid [][]Tile, rules []Rule, iterations int, opts Options) ([][]Tile, [][][]Tile, error) {
 	height := len(grid)
 	if height == 0 {
-		return nil, errors.New("grid height is zero")
+		return nil, nil, errors.New("grid height is zero")
 	}
 	width := len(grid[0])
+	nbh := opts.Neighborhood
+	if len(nbh) == 0 {
+		nbh = neighborhood.Default
+	}
+
+	var frames [][][]Tile
+	if opts.SnapshotEvery > 0 {
+		frames = append(frames, cloneGrid(grid))
+	}
+
+	// Double buffer owned by this call; the caller's grid is left untouched
+	grid = cloneGrid(grid)
+	next := make([][]Tile, height)
+	for y := range next {
+		next[y] = make([]Tile, width)
+	}
+	buffers := make([][]Tile, parallel.Bands(height, opts.Workers))
 
 	for i := 0; i < iterations; i++ {
-		nextGrid := make([][]Tile, height)
-		for y := 0; y < height; y++ {
-			nextGrid[y] = make([]Tile, width)
-			for x := 0; x < width; x++ {
-				neighbors := getAdjacentTiles(grid, x, y, width, height)
-				currentTile := grid[y][x]
-				applied := false
-				for _, rule := range rules {
-					if rule.Applies(currentTile, neighbors) {
-						nextGrid[y][x] = Tile{State: rule.TargetState}
-						applied = true
-						break
-					}
-				}
-				if !applied {
-					nextGrid[y][x] = currentTile
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		parallel.Rows(height, opts.Workers, func(band, y0, y1 int) {
+			neighbors := buffers[band]
+			for y := y0; y < y1; y++ {
+				for x := 0; x < width; x++ {
+					neighbors = gatherNeighbors(neighbors[:0], grid, x, y, width, height, nbh, opts)
+					next[y][x] = applyRules(grid[y][x], neighbors, rules)
 				}
 			}
+			buffers[band] = neighbors
+		})
+		grid, next = next, grid
+		if opts.Progress != nil {
+			opts.Progress(i+1, iterations)
+		}
+		if opts.Watch != nil {
+			opts.Watch(grid)
+		}
+		if opts.SnapshotEvery > 0 && (i+1)%opts.SnapshotEvery == 0 {
+			frames = append(frames, cloneGrid(grid))
 		}
-		grid = nextGrid
+	}
+	if opts.SnapshotEvery > 0 && iterations%opts.SnapshotEvery != 0 {
+		frames = append(frames, cloneGrid(grid))
 	}
 
-	return grid, nil
+	return grid, frames, nil
+}
+
+func cloneGrid(grid [][]Tile) [][]Tile {
+	out := make([][]Tile, len(grid))
+	for y := range grid {
+		out[y] = append([]Tile(nil), grid[y]...)
+	}
+	return out
 }
 
 func countNeighbors(neighbors []Tile, targetState tiles.TileType) int {
@@ -93,21 +140,36 @@ func countNeighbors(neighbors []Tile, targetState tiles.TileType) int {
 	return count
 }
 
-func getAdjacentTiles(grid [][]Tile, x, y, width, height int) []Tile {
-	dirs := []struct{ dx, dy int }{
-		{-1, -1}, {-1, 0}, {-1, 1},
-		{0, -1}, {0, 1},
-		{1, -1}, {1, 0}, {1, 1},
+// applyRules returns the next state of t under the first applicable rule.
+func applyRules(t Tile, neighbors []Tile, rules []Rule) Tile {
+	for _, rule := range rules {
+		if rule.Applies(t, neighbors) {
+			return Tile{State: rule.TargetState}
+		}
 	}
-	var neighbors []Tile
-	for _, d := range dirs {
-		nx, ny := x+d.dx, y+d.dy
-		if nx >= 0 && nx < width && ny >= 0 && ny < height {
-			neighbors = append(neighbors, grid[ny][nx])
+	return t
+}
+
+// gatherNeighbors appends the neighbors in nbh to buf, resolving positions
+// outside the grid by opts.Boundary.
+func gatherNeighbors(buf []Tile, grid [][]Tile, x, y, width, height int, nbh neighborhood.Neighborhood, opts Options) []Tile {
+	for _, o := range nbh {
+		nx, ny, ok := opts.Boundary.Resolve(x+o.DX, y+o.DY, width, height)
+		var t Tile
+		if ok {
+			t = grid[ny][nx]
+		} else if opts.Boundary == neighborhood.BoundaryFixed {
+			t = Tile{State: opts.BoundaryTile}
+		} else {
+			continue
+		}
+		for n := o.Times(); n > 0; n-- {
+			buf = append(buf, t)
 		}
 	}
-	return neighbors
+	return buf
 }
+
 func TilesToIntGrid(grid [][]Tile) [][]int {
 	res := make([][]int, len(grid))
 	for y := range grid {
@@ -121,22 +183,16 @@ func TilesToIntGrid(grid [][]Tile) [][]int {
 
 // Rules for Conway’s Game of Life
 func LifeRules() []Rule {
-	return []Rule{
-
-		// Survive with 2 or 3 living neighbors
-		{CurrentState: alive, NeighborState: alive, MinCount: 2, MaxCount: 3, TargetState: alive},
-		// Birth with 3 living neighbors
-		{CurrentState: dead, NeighborState: alive, MinCount: 3, MaxCount: 3, TargetState: alive},
-		// Under-/Overpopulation → DEATH
-		{CurrentState: anyState, NeighborState: alive, MinCount: 0, MaxCount: 1, TargetState: dead},
-		{CurrentState: anyState, NeighborState: alive, MinCount: 4, MaxCount: -1, TargetState: dead},
-	}
+	return LifeRule{Birth: []int{3}, Survive: []int{2, 3}}.Rules(alive, dead)
 }
 
-func NewGrid(width, height int) [][]Tile {
-	return InitializeGrid(width, height)
+// NewGrid fills half the cells of a width x height grid at random with
+// DefaultAlive and DefaultDead.
+func NewGrid(width, height int, rng *rand.Rand) [][]Tile {
+	grid, _ := InitializeGrid(width, height, rng, InitOptions{Alive: DefaultAlive, Dead: DefaultDead})
+	return grid
 }
 
-func StepCA(grid [][]Tile, iterations int) ([][]Tile, error) {
-	return ApplyCARules(grid, LifeRules(), iterations)
+func StepCA(ctx context.Context, grid [][]Tile, iterations int, opts Options) ([][]Tile, [][][]Tile, error) {
+	return ApplyCARules(ctx, grid, LifeRules(), iterations, opts)
 }
@@ -0,0 +1,43 @@
+package gol
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestDeterministicAcrossWorkers(t *testing.T) {
+	start := NewGrid(64, 64, rand.New(rand.NewSource(1)))
+	want, _, err := StepCA(context.Background(), start, 20, Options{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{2, 3, runtime.NumCPU()} {
+		got, _, err := StepCA(context.Background(), start, 20, Options{Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers differ from 1 worker", workers)
+		}
+	}
+}
+
+func BenchmarkApplyRules(b *testing.B) {
+	start := NewGrid(512, 512, rand.New(rand.NewSource(1)))
+	counts := []int{1, 2, 4, runtime.NumCPU()}
+	slices.Sort(counts)
+	for _, workers := range slices.Compact(counts) {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := StepCA(context.Background(), start, 50, Options{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
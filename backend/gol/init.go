@@ -0,0 +1,129 @@
+package gol
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/aquilax/go-perlin"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// Pattern selects how the initial grid is filled.
+type Pattern string
+
+const (
+	PatternRandom Pattern = "random" // each cell is alive with probability Density
+	PatternNoise  Pattern = "noise"  // the Density share of cells with the highest Perlin noise is alive
+	PatternBorder Pattern = "border" // random interior inside a live border
+)
+
+// ParsePattern maps a request value to a Pattern. Empty gives PatternRandom.
+func ParsePattern(s string) (Pattern, error) {
+	switch p := Pattern(s); p {
+	case "":
+		return PatternRandom, nil
+	case PatternRandom, PatternNoise, PatternBorder:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown initial pattern %q", s)
+}
+
+// InitOptions controls InitializeGrid. Unset fields fill half the cells at
+// random.
+type InitOptions struct {
+	// Alive and Dead are the tile types of live and dead cells; they must
+	// differ unless States is set.
+	Alive, Dead tiles.TileType
+	// Density is the share of live cells in [0, 1]; nil means 0.5.
+	Density *float64
+	Pattern Pattern
+	// NoiseScale is the number of noise periods across the grid for
+	// PatternNoise; zero means 4.
+	NoiseScale float64
+	// BorderWidth is the width of the live border for PatternBorder; zero means 1.
+	BorderWidth int
+	// States, when set, replaces the alive/dead fill: every cell is drawn
+	// uniformly from States, as cyclic CA need. Density and Pattern are ignored.
+	States []tiles.TileType
+}
+
+// InitializeGrid fills a width x height grid from rng according to opts.
+func InitializeGrid(width, height int, rng *rand.Rand, opts InitOptions) ([][]Tile, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid grid size %dx%d", width, height)
+	}
+	if opts.Alive == opts.Dead && len(opts.States) == 0 {
+		return nil, fmt.Errorf("alive and dead tiles must differ")
+	}
+	density := lifeProbability
+	if opts.Density != nil {
+		density = *opts.Density
+	}
+	if density < 0 || density > 1 {
+		return nil, fmt.Errorf("density %g outside [0, 1]", density)
+	}
+	if opts.NoiseScale <= 0 {
+		opts.NoiseScale = 4
+	}
+	if opts.BorderWidth <= 0 {
+		opts.BorderWidth = 1
+	}
+
+	grid := make([][]Tile, height)
+	for y := range grid {
+		grid[y] = make([]Tile, width)
+	}
+	if len(opts.States) > 0 {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				grid[y][x] = Tile{State: opts.States[rng.Intn(len(opts.States))]}
+			}
+		}
+		return grid, nil
+	}
+	set := func(x, y int, live bool) {
+		if live {
+			grid[y][x] = Tile{State: opts.Alive}
+		} else {
+			grid[y][x] = Tile{State: opts.Dead}
+		}
+	}
+
+	switch opts.Pattern {
+	case "", PatternRandom:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				set(x, y, rng.Float64() < density)
+			}
+		}
+	case PatternBorder:
+		b := opts.BorderWidth
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				edge := x < b || y < b || x >= width-b || y >= height-b
+				set(x, y, edge || rng.Float64() < density)
+			}
+		}
+	case PatternNoise:
+		p := perlin.NewPerlin(2, 2, 3, rng.Int63())
+		values := make([]float64, 0, width*height)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				nx := float64(x) / float64(width) * opts.NoiseScale
+				ny := float64(y) / float64(height) * opts.NoiseScale
+				values = append(values, p.Noise2D(nx, ny))
+			}
+		}
+		// Threshold at the quantile that makes the live share match Density
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+		live := int(density*float64(len(sorted)) + 0.5)
+		for i, v := range values {
+			set(i%width, i/width, live > 0 && v >= sorted[len(sorted)-live])
+		}
+	default:
+		return nil, fmt.Errorf("unknown initial pattern %q", opts.Pattern)
+	}
+	return grid, nil
+}
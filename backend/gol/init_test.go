@@ -0,0 +1,115 @@
+package gol
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// liveShare returns the share of cells in state alive.
+func liveShare(grid [][]Tile, alive tiles.TileType) float64 {
+	live, total := 0, 0
+	for _, row := range grid {
+		for _, c := range row {
+			if c.State == alive {
+				live++
+			}
+			total++
+		}
+	}
+	return float64(live) / float64(total)
+}
+
+func TestInitializeGrid(t *testing.T) {
+	p := func(v float64) *float64 { return &v }
+	tests := []struct {
+		pattern Pattern
+		density *float64
+		// share is the expected live share, matched within tolerance
+		share, tolerance float64
+	}{
+		{PatternRandom, nil, 0.5, 0.05},
+		{PatternRandom, p(0), 0, 0},
+		{PatternRandom, p(1), 1, 0},
+		{PatternRandom, p(0.2), 0.2, 0.05},
+		// Noise thresholds at the exact quantile
+		{PatternNoise, p(0), 0, 0},
+		{PatternNoise, p(0.3), 0.3, 0.001},
+		{PatternNoise, p(1), 1, 0},
+		// The one-cell border of a 40x40 grid is 156 cells
+		{PatternBorder, p(0), 156.0 / 1600, 0},
+	}
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s/default", tt.pattern)
+		if tt.density != nil {
+			name = fmt.Sprintf("%s/%g", tt.pattern, *tt.density)
+		}
+		t.Run(name, func(t *testing.T) {
+			opts := InitOptions{Alive: DefaultAlive, Dead: DefaultDead, Pattern: tt.pattern, Density: tt.density}
+			grid, err := InitializeGrid(40, 40, rand.New(rand.NewSource(7)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if share := liveShare(grid, DefaultAlive); math.Abs(share-tt.share) > tt.tolerance {
+				t.Errorf("live share %g, want %g", share, tt.share)
+			}
+			// The same seed gives the same grid
+			again, err := InitializeGrid(40, 40, rand.New(rand.NewSource(7)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(grid, again) {
+				t.Error("two grids from the same seed differ")
+			}
+		})
+	}
+}
+
+func TestInitializeGridStates(t *testing.T) {
+	states := []tiles.TileType{tiles.Water, tiles.Grass, tiles.Forest}
+	grid, err := InitializeGrid(30, 30, rand.New(rand.NewSource(1)), InitOptions{States: states})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range states {
+		if share := liveShare(grid, s); math.Abs(share-1.0/3) > 0.06 {
+			t.Errorf("state %d has share %g, want about 1/3", s, share)
+		}
+	}
+}
+
+func TestInitializeGridTileZero(t *testing.T) {
+	grid, err := InitializeGrid(10, 10, rand.New(rand.NewSource(1)), InitOptions{Alive: tiles.DeepWater, Dead: tiles.Sand})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share := liveShare(grid, tiles.DeepWater); share == 0 || share+liveShare(grid, tiles.Sand) != 1 {
+		t.Errorf("deep water share %g, want live cells of tile 0 on sand", share)
+	}
+}
+
+func TestInitializeGridErrors(t *testing.T) {
+	p := func(v float64) *float64 { return &v }
+	tests := []struct {
+		width, height int
+		opts          InitOptions
+	}{
+		{0, 10, InitOptions{Alive: DefaultAlive}},
+		{10, -1, InitOptions{Alive: DefaultAlive}},
+		{10, 10, InitOptions{Alive: DefaultAlive, Density: p(-0.1)}},
+		{10, 10, InitOptions{Alive: DefaultAlive, Density: p(1.5)}},
+		{10, 10, InitOptions{Alive: DefaultAlive, Pattern: "stripes"}},
+		// Tile 0 is a tile like any other, so both zero are the same tile
+		{10, 10, InitOptions{}},
+		{10, 10, InitOptions{Alive: tiles.Grass, Dead: tiles.Grass}},
+	}
+	for _, tt := range tests {
+		if _, err := InitializeGrid(tt.width, tt.height, rand.New(rand.NewSource(1)), tt.opts); err == nil {
+			t.Errorf("InitializeGrid(%d, %d, %+v) succeeded, want an error", tt.width, tt.height, tt.opts)
+		}
+	}
+}
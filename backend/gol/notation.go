@@ -0,0 +1,175 @@
+package gol
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// LifeRule is a Life-like rule: the neighbor counts at which a dead cell is
+// born and at which a live cell survives.
+type LifeRule struct {
+	Birth   []int
+	Survive []int
+}
+
+// Presets maps names to common Life-like rules.
+var Presets = map[string]string{
+	"conway":    "B3/S23",
+	"highlife":  "B36/S23",
+	"daynight":  "B3678/S34678",
+	"seeds":     "B2/S",
+	"caves":     "B678/S345678",
+	"cave45":    "B5678/S45678", // the classic 4-5 cave smoothing rule
+	"maze":      "B3/S12345",
+	"mazectric": "B3/S1234",
+
+	// Generations rules
+	"brianbrain": "B2/S/C3",
+	"starwars":   "B2/S345/C4",
+	"succession": "B3/S2345/C4", // slowly spreading vegetation with a decay fringe
+}
+
+// ParseLifeRule parses a preset name or B/S notation such as "B3/S23" or
+// "S23/B3". Counts above 9, needed for larger neighborhoods, can be written as
+// a comma-separated list with ranges: "B10-12/S8,9,14".
+func ParseLifeRule(s string) (LifeRule, error) {
+	r, states, err := parseNotation(s)
+	if err == nil && states != 0 && states != 2 {
+		err = fmt.Errorf("rule %q has decay states; use ParseGenerations", s)
+	}
+	return r, err
+}
+
+// parseNotation parses B/S or B/S/C notation. states is zero without a C part.
+func parseNotation(s string) (LifeRule, int, error) {
+	if preset, ok := Presets[strings.ToLower(s)]; ok {
+		s = preset
+	}
+	var r LifeRule
+	states := 0
+	var haveB, haveS bool
+	for _, part := range strings.Split(strings.ToUpper(strings.TrimSpace(s)), "/") {
+		if part == "" {
+			return r, 0, fmt.Errorf("invalid rule %q", s)
+		}
+		if part[0] == 'C' && states == 0 {
+			n, err := strconv.Atoi(part[1:])
+			if err != nil || n < 2 {
+				return r, 0, fmt.Errorf("invalid rule %q: state count must be at least 2", s)
+			}
+			states = n
+			continue
+		}
+		counts, err := parseCounts(part[1:])
+		if err != nil {
+			return r, 0, fmt.Errorf("invalid rule %q: %w", s, err)
+		}
+		switch {
+		case part[0] == 'B' && !haveB:
+			r.Birth, haveB = counts, true
+		case part[0] == 'S' && !haveS:
+			r.Survive, haveS = counts, true
+		default:
+			return r, 0, fmt.Errorf("invalid rule %q", s)
+		}
+	}
+	if !haveB || !haveS {
+		return r, 0, fmt.Errorf("invalid rule %q: need B and S parts", s)
+	}
+	return r, states, nil
+}
+
+func parseCounts(s string) ([]int, error) {
+	var counts []int
+	if !strings.ContainsAny(s, ",-") {
+		for _, c := range s {
+			if c < '0' || c > '9' {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			counts = append(counts, int(c-'0'))
+		}
+		return normalizeCounts(counts), nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(item, "-")
+		from, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(hi); err != nil {
+				return nil, err
+			}
+		}
+		if from < 0 || to < from {
+			return nil, fmt.Errorf("invalid count range %q", item)
+		}
+		for c := from; c <= to; c++ {
+			counts = append(counts, c)
+		}
+	}
+	return normalizeCounts(counts), nil
+}
+
+func normalizeCounts(counts []int) []int {
+	sort.Ints(counts)
+	out := counts[:0]
+	for i, c := range counts {
+		if i == 0 || c != counts[i-1] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// String returns the rule in B/S notation.
+func (r LifeRule) String() string {
+	return "B" + formatCounts(r.Birth) + "/S" + formatCounts(r.Survive)
+}
+
+func formatCounts(counts []int) string {
+	var b strings.Builder
+	wide := len(counts) > 0 && counts[len(counts)-1] > 9
+	for i, c := range counts {
+		if wide && i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(c))
+	}
+	return b.String()
+}
+
+// Rules compiles the rule for the given alive and dead tile types. Cells of
+// any other type count as dead.
+func (r LifeRule) Rules(alive, dead tiles.TileType) []Rule {
+	var rules []Rule
+	for _, span := range spans(r.Survive) {
+		rules = append(rules, Rule{CurrentState: alive, NeighborState: alive, MinCount: span[0], MaxCount: span[1], TargetState: alive})
+	}
+	// Live cells that do not survive die
+	rules = append(rules, Rule{CurrentState: alive, NeighborState: alive, MinCount: 0, MaxCount: -1, TargetState: dead})
+	for _, span := range spans(r.Birth) {
+		rules = append(rules, Rule{CurrentState: anyState, NeighborState: alive, MinCount: span[0], MaxCount: span[1], TargetState: alive})
+	}
+	// Everything else stays or becomes dead
+	rules = append(rules, Rule{CurrentState: anyState, NeighborState: alive, MinCount: 0, MaxCount: -1, TargetState: dead})
+	return rules
+}
+
+// spans merges sorted counts into inclusive [min, max] ranges.
+func spans(counts []int) [][2]int {
+	var out [][2]int
+	for _, c := range counts {
+		if n := len(out); n > 0 && out[n-1][1] == c-1 {
+			out[n-1][1] = c
+			continue
+		}
+		out = append(out, [2]int{c, c})
+	}
+	return out
+}
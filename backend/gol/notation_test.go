@@ -0,0 +1,92 @@
+package gol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func TestParseLifeRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		birth   []int
+		survive []int
+		// str is the String form; empty expects an error
+		str string
+	}{
+		{"B3/S23", []int{3}, []int{2, 3}, "B3/S23"},
+		{"s23/b3", []int{3}, []int{2, 3}, "B3/S23"},
+		{"conway", []int{3}, []int{2, 3}, "B3/S23"},
+		{"HighLife", []int{3, 6}, []int{2, 3}, "B36/S23"},
+		{"seeds", []int{2}, nil, "B2/S"},
+		{"B33/S32", []int{3}, []int{2, 3}, "B3/S23"},
+		{"B10-12/S8,9,14", []int{10, 11, 12}, []int{8, 9, 14}, "B10,11,12/S8,9,14"},
+		{"B3", nil, nil, ""},
+		{"B3/S23/B4", nil, nil, ""},
+		{"B3x/S23", nil, nil, ""},
+		{"B5-2/S1", nil, nil, ""},
+		{"B3//S23", nil, nil, ""},
+		{"B2/S/C3", nil, nil, ""},
+		{"brianbrain", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		r, err := ParseLifeRule(tt.in)
+		if tt.str == "" {
+			if err == nil {
+				t.Errorf("ParseLifeRule(%q) = %s, want an error", tt.in, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseLifeRule(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(r.Birth, tt.birth) || !reflect.DeepEqual(r.Survive, tt.survive) || r.String() != tt.str {
+			t.Errorf("ParseLifeRule(%q) = %v/%v %s, want %v/%v %s", tt.in, r.Birth, r.Survive, r, tt.birth, tt.survive, tt.str)
+		}
+	}
+}
+
+// gridOf builds a grid from rows of '#' (alive) and '.' (dead).
+func gridOf(rows ...string) [][]Tile {
+	grid := make([][]Tile, len(rows))
+	for y, row := range rows {
+		grid[y] = make([]Tile, len(row))
+		for x, c := range row {
+			grid[y][x] = Tile{State: tiles.Sand}
+			if c == '#' {
+				grid[y][x] = Tile{State: tiles.Bushes}
+			}
+		}
+	}
+	return grid
+}
+
+func TestLifeRuleSteps(t *testing.T) {
+	tests := []struct {
+		rule        string
+		start, want [][]Tile
+	}{
+		// The blinker oscillates under Conway's rule
+		{"conway", gridOf(".....", "..#..", "..#..", "..#..", "....."), gridOf(".....", ".....", ".###.", ".....", ".....")},
+		// Seeds: every live cell dies, cells with two live neighbors are born
+		{"seeds", gridOf("....", ".##.", "...."), gridOf(".##.", "....", ".##.")},
+		// The block is stable under HighLife
+		{"highlife", gridOf("....", ".##.", ".##.", "...."), gridOf("....", ".##.", ".##.", "....")},
+	}
+	for _, tt := range tests {
+		r, err := ParseLifeRule(tt.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := ApplyCARules(context.Background(), tt.start, r.Rules(tiles.Bushes, tiles.Sand), 1, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: one step gave %v, want %v", tt.rule, TilesToIntGrid(got), TilesToIntGrid(tt.want))
+		}
+	}
+}
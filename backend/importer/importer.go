@@ -0,0 +1,178 @@
+// Package importer turns images of maps, e.g. canvas exports, back into tile
+// grids by snapping colors to the tile palette.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	// Decoders for image.Decode
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"procedural-map-generation-toolkit/backend/render"
+)
+
+const (
+	// DefaultBlockSize is the editor's tile size in pixels.
+	DefaultBlockSize = 20
+	// DefaultTolerance is the largest RGB distance still matching a color.
+	DefaultTolerance = 32.0
+)
+
+// Options configures an import; zero values select the defaults.
+type Options struct {
+	// BlockSize is the number of pixels per tile in both directions.
+	BlockSize int
+	// Tolerance is the largest Euclidean RGB distance (0..441) at which a
+	// pixel still matches its nearest palette color.
+	Tolerance float64
+	// MaxPixels and MaxCells, when positive, reject images with more pixels
+	// or grid cells, and MaxWidth and MaxHeight wider or higher grids. Decode
+	// checks them before decoding the pixels.
+	MaxPixels           int
+	MaxCells            int
+	MaxWidth, MaxHeight int
+}
+
+func (o *Options) defaults() {
+	if o.BlockSize <= 0 {
+		o.BlockSize = DefaultBlockSize
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = DefaultTolerance
+	}
+}
+
+// checkSize checks a width x height pixel image against the maxima.
+func (o Options) checkSize(width, height int) error {
+	if o.MaxPixels > 0 && width*height > o.MaxPixels {
+		return fmt.Errorf("image is %dx%d pixels, more than %d", width, height, o.MaxPixels)
+	}
+	cols, rows := (width+o.BlockSize-1)/o.BlockSize, (height+o.BlockSize-1)/o.BlockSize
+	if (o.MaxWidth > 0 && cols > o.MaxWidth) || (o.MaxHeight > 0 && rows > o.MaxHeight) {
+		return fmt.Errorf("the grid would be %dx%d cells, more than %dx%d", cols, rows, o.MaxWidth, o.MaxHeight)
+	}
+	if cells := cols * rows; o.MaxCells > 0 && cells > o.MaxCells {
+		return fmt.Errorf("the grid would have %d cells, more than %d", cells, o.MaxCells)
+	}
+	return nil
+}
+
+// Result is an imported grid. Cells without a single matching pixel are -1.
+type Result struct {
+	Grid   [][]int `json:"grid"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	// UnmatchedPixels counts opaque pixels farther than the tolerance from
+	// every palette color
+	UnmatchedPixels int `json:"unmatchedPixels"`
+	// UnmatchedCells counts cells set to -1
+	UnmatchedCells int `json:"unmatchedCells"`
+}
+
+// Decode reads a PNG, GIF or JPEG image and imports it. The size the image
+// declares is checked before its pixels are decoded, so a small file cannot
+// claim a huge image.
+func Decode(r io.Reader, colors []string, opts Options) (*Result, error) {
+	opts.defaults()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.checkSize(cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return Import(img, colors, opts)
+}
+
+// Import snaps every pixel of img to the nearest of colors and gives each
+// BlockSize x BlockSize block the tile most of its matching pixels snapped
+// to. Partial blocks at the right and bottom edge become cells too.
+// Transparent pixels are ignored.
+func Import(img image.Image, colors []string, opts Options) (*Result, error) {
+	opts.defaults()
+	palette, err := render.Palette(colors)
+	if err != nil {
+		return nil, err
+	}
+	if len(palette) == 0 {
+		return nil, errors.New("empty palette")
+	}
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, errors.New("empty image")
+	}
+	if err := opts.checkSize(bounds.Dx(), bounds.Dy()); err != nil {
+		return nil, err
+	}
+
+	bs := opts.BlockSize
+	res := &Result{
+		Width:  (bounds.Dx() + bs - 1) / bs,
+		Height: (bounds.Dy() + bs - 1) / bs,
+	}
+	maxDist := opts.Tolerance * opts.Tolerance
+	votes := make([]int, len(palette))
+	res.Grid = make([][]int, res.Height)
+	for cy := 0; cy < res.Height; cy++ {
+		res.Grid[cy] = make([]int, res.Width)
+		for cx := 0; cx < res.Width; cx++ {
+			clear(votes)
+			for py := bounds.Min.Y + cy*bs; py < bounds.Min.Y+(cy+1)*bs && py < bounds.Max.Y; py++ {
+				for px := bounds.Min.X + cx*bs; px < bounds.Min.X+(cx+1)*bs && px < bounds.Max.X; px++ {
+					r, g, b, a := img.At(px, py).RGBA()
+					if a < 0x8000 {
+						continue
+					}
+					best, bestDist := nearest(palette, r>>8, g>>8, b>>8)
+					if bestDist > maxDist {
+						res.UnmatchedPixels++
+						continue
+					}
+					votes[best]++
+				}
+			}
+			tile := -1
+			for t, n := range votes {
+				if n > 0 && (tile < 0 || n > votes[tile]) {
+					tile = t
+				}
+			}
+			if tile < 0 {
+				res.UnmatchedCells++
+			}
+			res.Grid[cy][cx] = tile
+		}
+	}
+	return res, nil
+}
+
+// nearest returns the index of the palette color closest to r, g, b and the
+// squared distance to it.
+func nearest(palette color.Palette, r, g, b uint32) (int, float64) {
+	best, bestDist := 0, -1.0
+	for i, c := range palette {
+		pr, pg, pb, _ := c.RGBA()
+		dr := float64(r) - float64(pr>>8)
+		dg := float64(g) - float64(pg>>8)
+		db := float64(b) - float64(pb>>8)
+		if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best, bestDist
+}
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"strings"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/render"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	colors := tiles.Default.Colors()
+	tests := []struct {
+		name     string
+		grid     [][]int
+		cellSize int
+		opts     Options
+		// unmatched is the number of -1 cells
+		unmatched int
+	}{
+		{"every tile", [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}}, DefaultBlockSize, Options{}, 0},
+		{"small blocks", [][]int{{7, 6}, {1, 0}, {3, 3}}, 4, Options{BlockSize: 4}, 0},
+		{"unpainted cells", [][]int{{-1, 2}, {5, -1}}, 8, Options{BlockSize: 8}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := render.PNG(tt.grid, colors, render.Options{CellSize: tt.cellSize})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := Decode(bytes.NewReader(data), colors, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res.Grid, tt.grid) {
+				t.Errorf("grid = %v, want %v", res.Grid, tt.grid)
+			}
+			if res.Width != len(tt.grid[0]) || res.Height != len(tt.grid) {
+				t.Errorf("size = %dx%d, want %dx%d", res.Width, res.Height, len(tt.grid[0]), len(tt.grid))
+			}
+			if res.UnmatchedCells != tt.unmatched {
+				t.Errorf("UnmatchedCells = %d, want %d", res.UnmatchedCells, tt.unmatched)
+			}
+		})
+	}
+}
+
+// pngHeader returns a PNG that declares a width x height image but holds
+// no pixel data.
+func pngHeader(width, height uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 2 // truecolor
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+func TestDecodeRejectsLargeImages(t *testing.T) {
+	colors := tiles.Default.Colors()
+	small, err := render.PNG([][]int{{0, 1, 2}, {3, 4, 5}}, colors, render.Options{CellSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		opts Options
+		want string
+	}{
+		{"declared pixels", pngHeader(60000, 60000), Options{MaxPixels: 64 << 20}, "more than 67108864"},
+		{"declared cells", pngHeader(60000, 60000), Options{MaxCells: 1 << 20}, "more than 1048576"},
+		{"pixels", small, Options{MaxPixels: 500}, "30x20 pixels"},
+		{"cells", small, Options{BlockSize: 5, MaxCells: 20}, "24 cells"},
+		{"width", small, Options{BlockSize: 5, MaxWidth: 5, MaxHeight: 10}, "6x4 cells"},
+		{"height", small, Options{BlockSize: 5, MaxWidth: 10, MaxHeight: 3}, "6x4 cells"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(tt.data), colors, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Decode error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
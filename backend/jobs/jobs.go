@@ -0,0 +1,230 @@
+// Package jobs runs long generations in the background on a bounded worker
+// pool, with progress reporting and cancellation.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusRunning  Status = "running"
+	StatusDone     Status = "done"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+)
+
+// Finished reports whether the job will not change any more.
+func (s Status) Finished() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCanceled
+}
+
+// Func is the work of a job. It must return once ctx is canceled and may call
+// progress with the number of steps done and the total.
+type Func func(ctx context.Context, progress func(done, total int)) (any, error)
+
+var (
+	// ErrQueueFull is returned by Submit when all queue slots are taken.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrNotFound is returned for unknown or pruned job ids.
+	ErrNotFound = errors.New("job not found")
+)
+
+// Progress counts the steps of a running job, e.g. iterations or collapsed
+// cells.
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Snapshot is a copy of the state of a job.
+type Snapshot struct {
+	ID       string     `json:"id"`
+	Status   Status     `json:"status"`
+	Progress Progress   `json:"progress"`
+	Result   any        `json:"result,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+type job struct {
+	snap   Snapshot
+	fn     Func
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Options configures a Manager; zero values select the defaults.
+type Options struct {
+	// Workers is the number of jobs run at once (default 2).
+	Workers int
+	// Queue is the number of jobs waiting for a worker (default 16).
+	Queue int
+	// TTL is how long finished jobs are kept (default 10 minutes).
+	TTL time.Duration
+}
+
+// Manager queues jobs and runs them on a fixed number of workers.
+type Manager struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	// queue holds the queued jobs in submission order; ready signals workers
+	// that it is not empty
+	queue    []*job
+	maxQueue int
+	ready    *sync.Cond
+	ttl      time.Duration
+}
+
+// NewManager starts the workers of a Manager.
+func NewManager(opts Options) *Manager {
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.Queue <= 0 {
+		opts.Queue = 16
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 10 * time.Minute
+	}
+	m := &Manager{
+		jobs:     make(map[string]*job),
+		maxQueue: opts.Queue,
+		ttl:      opts.TTL,
+	}
+	m.ready = sync.NewCond(&m.mu)
+	for i := 0; i < opts.Workers; i++ {
+		go m.work()
+	}
+	return m
+}
+
+// Submit queues fn and returns the new job's id.
+func (m *Manager) Submit(fn Func) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		snap:   Snapshot{ID: id, Status: StatusQueued, Created: time.Now()},
+		fn:     fn,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	if len(m.queue) >= m.maxQueue {
+		cancel()
+		return "", ErrQueueFull
+	}
+	m.queue = append(m.queue, j)
+	m.jobs[id] = j
+	m.ready.Signal()
+	return id, nil
+}
+
+// Get returns a snapshot of the job with the given id.
+func (m *Manager) Get(id string) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Snapshot{}, ErrNotFound
+	}
+	return j.snap, nil
+}
+
+// Cancel stops the job with the given id. Queued jobs are canceled at once
+// and free their queue slot, running jobs once their generator notices;
+// finished jobs are left as is.
+func (m *Manager) Cancel(id string) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Snapshot{}, ErrNotFound
+	}
+	j.cancel()
+	if j.snap.Status == StatusQueued {
+		m.queue = slices.DeleteFunc(m.queue, func(q *job) bool { return q == j })
+		m.finish(j, nil, context.Canceled)
+	}
+	return j.snap, nil
+}
+
+func (m *Manager) work() {
+	for {
+		m.mu.Lock()
+		for len(m.queue) == 0 {
+			m.ready.Wait()
+		}
+		j := m.queue[0]
+		m.queue[0] = nil
+		m.queue = m.queue[1:]
+		now := time.Now()
+		j.snap.Status = StatusRunning
+		j.snap.Started = &now
+		m.mu.Unlock()
+
+		result, err := j.fn(j.ctx, func(done, total int) {
+			m.mu.Lock()
+			j.snap.Progress = Progress{Done: done, Total: total}
+			m.mu.Unlock()
+		})
+
+		m.mu.Lock()
+		m.finish(j, result, err)
+		m.mu.Unlock()
+		j.cancel()
+	}
+}
+
+// finish records the outcome of j; m.mu must be held. Work that completes
+// despite a late cancellation counts as done.
+func (m *Manager) finish(j *job, result any, err error) {
+	now := time.Now()
+	j.snap.Finished = &now
+	switch {
+	case err == nil:
+		j.snap.Status = StatusDone
+		j.snap.Result = result
+	case j.ctx.Err() != nil && errors.Is(err, context.Canceled):
+		j.snap.Status = StatusCanceled
+	default:
+		j.snap.Status = StatusFailed
+		j.snap.Error = err.Error()
+	}
+}
+
+// prune drops jobs finished longer than the TTL ago; m.mu must be held.
+func (m *Manager) prune() {
+	cutoff := time.Now().Add(-m.ttl)
+	for id, j := range m.jobs {
+		if j.snap.Finished != nil && j.snap.Finished.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
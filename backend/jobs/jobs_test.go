@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// wait polls the job with the given id until it finishes.
+func wait(t *testing.T, m *Manager, id string) Snapshot {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		snap, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.Status.Finished() {
+			return snap
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Snapshot{}
+}
+
+// blocking returns a Func that reports started once running and then waits
+// for release or cancellation.
+func blocking(started chan<- struct{}, release <-chan struct{}) Func {
+	return func(ctx context.Context, progress func(done, total int)) (any, error) {
+		close(started)
+		select {
+		case <-release:
+			return "released", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestJobOutcome(t *testing.T) {
+	failure := errors.New("generation failed")
+	tests := []struct {
+		name   string
+		fn     Func
+		status Status
+		result any
+		err    string
+	}{
+		{"done", func(ctx context.Context, progress func(done, total int)) (any, error) {
+			progress(3, 3)
+			return 42, nil
+		}, StatusDone, 42, ""},
+		{"failed", func(ctx context.Context, progress func(done, total int)) (any, error) {
+			return nil, failure
+		}, StatusFailed, nil, failure.Error()},
+	}
+	m := NewManager(Options{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := m.Submit(tt.fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			snap := wait(t, m, id)
+			if snap.Status != tt.status || snap.Result != tt.result || snap.Error != tt.err {
+				t.Errorf("got %s %v %q, want %s %v %q", snap.Status, snap.Result, snap.Error, tt.status, tt.result, tt.err)
+			}
+			if snap.Started == nil || snap.Finished == nil {
+				t.Error("start or finish time missing")
+			}
+			if tt.status == StatusDone && snap.Progress != (Progress{Done: 3, Total: 3}) {
+				t.Errorf("progress %+v, want 3/3", snap.Progress)
+			}
+		})
+	}
+	if _, err := m.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrNotFound", err)
+	}
+	if _, err := m.Cancel("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestQueueAndCancel(t *testing.T) {
+	m := NewManager(Options{Workers: 1, Queue: 1})
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	running, err := m.Submit(blocking(started, release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	queued, err := m.Submit(blocking(make(chan struct{}), release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Submit(blocking(make(chan struct{}), release)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("third Submit = %v, want ErrQueueFull", err)
+	}
+
+	// A queued job is canceled at once, a running one once it returns
+	snap, err := m.Cancel(queued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Status != StatusCanceled || snap.Started != nil {
+		t.Errorf("queued job is %s after Cancel, want canceled without starting", snap.Status)
+	}
+	// The canceled job frees its queue slot
+	next, err := m.Submit(blocking(make(chan struct{}), release))
+	if err != nil {
+		t.Fatalf("Submit after canceling the queued job: %v", err)
+	}
+	if _, err := m.Cancel(next); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Cancel(running); err != nil {
+		t.Fatal(err)
+	}
+	if snap := wait(t, m, running); snap.Status != StatusCanceled {
+		t.Errorf("running job is %s after Cancel, want canceled", snap.Status)
+	}
+}
+
+func TestCancelAfterCompletion(t *testing.T) {
+	m := NewManager(Options{})
+	started, release := make(chan struct{}), make(chan struct{})
+	id, err := m.Submit(func(ctx context.Context, progress func(done, total int)) (any, error) {
+		close(started)
+		<-ctx.Done()
+		// The work was complete anyway
+		<-release
+		return "result", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if _, err := m.Cancel(id); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if snap := wait(t, m, id); snap.Status != StatusDone || snap.Result != "result" {
+		t.Errorf("got %s %v, want the completed result", snap.Status, snap.Result)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	m := NewManager(Options{TTL: 100 * time.Millisecond})
+	id, err := m.Submit(func(ctx context.Context, progress func(done, total int)) (any, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, m, id)
+	time.Sleep(150 * time.Millisecond)
+	if _, err := m.Get(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after the TTL = %v, want ErrNotFound", err)
+	}
+}
@@ -1,45 +1,119 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io/fs"
+	"io"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
-	"procedural-map-generation-toolkit/backend/gol"
+	"procedural-map-generation-toolkit/backend/generator"
+	_ "procedural-map-generation-toolkit/backend/generator/methods"
+	"procedural-map-generation-toolkit/backend/importer"
+	"procedural-map-generation-toolkit/backend/jobs"
+	"procedural-map-generation-toolkit/backend/mapfile"
+	"procedural-map-generation-toolkit/backend/mapstore"
 	"procedural-map-generation-toolkit/backend/metrics"
 	"procedural-map-generation-toolkit/backend/mlca"
-	"procedural-map-generation-toolkit/backend/noise"
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/render"
+	"procedural-map-generation-toolkit/backend/tiled"
 	"procedural-map-generation-toolkit/backend/tiles"
-	"procedural-map-generation-toolkit/backend/wfc"
+	"procedural-map-generation-toolkit/backend/timelapse"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-const fixedSeed = 1
-const defaultWFCSeed = 1
-
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "learn" {
+		if err := runLearnCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Rule learning failed: %v", err)
+		}
+		return
+	}
+
+	flag.IntVar(&limits.MaxWidth, "max-width", limits.MaxWidth, "largest map width")
+	flag.IntVar(&limits.MaxHeight, "max-height", limits.MaxHeight, "largest map height")
+	flag.IntVar(&limits.MaxCells, "max-cells", limits.MaxCells, "largest number of map cells")
+	flag.IntVar(&limits.MaxIterations, "max-iterations", limits.MaxIterations, "largest number of iterations")
+	flag.IntVar(&limits.MaxOctaves, "max-octaves", limits.MaxOctaves, "largest number of noise octaves")
+	flag.IntVar(&limits.MaxRadius, "max-radius", limits.MaxRadius, "largest neighborhood radius")
+	flag.IntVar(&limits.MaxFrames, "max-frames", limits.MaxFrames, "largest number of time-lapse frames")
+	flag.IntVar(&limits.MaxFrameCells, "max-frame-cells", limits.MaxFrameCells, "largest number of cells over all time-lapse frames")
+	flag.IntVar(&limits.MaxImagePixels, "max-image-pixels", limits.MaxImagePixels, "largest number of pixels in a rendered image")
+	flag.IntVar(&limits.MaxExamples, "max-examples", limits.MaxExamples, "largest number of example grids for rule learning")
+	flag.IntVar(&maxStages, "max-stages", maxStages, "largest number of pipeline stages")
+	bodyLimit := flag.String("max-body", "32M", "largest request body, e.g. 32M")
+	jobWorkers := flag.Int("job-workers", 2, "number of generation jobs run at once")
+	jobQueue := flag.Int("job-queue", 16, "number of generation jobs waiting for a worker")
+	tileSetDir := flag.String("tilesets", "tilesets", "directory of tile set JSON files to load")
+	flag.Parse()
+
+	if err := loadTileSets(*tileSetDir); err != nil {
+		log.Fatalf("Loading tile sets failed: %v", err)
+	}
+
+	jobManager = jobs.NewManager(jobs.Options{Workers: *jobWorkers, Queue: *jobQueue})
+	var err error
+	if maps, err = mapstore.Open(mapsDir); err != nil {
+		log.Fatalf("Opening the map library failed: %v", err)
+	}
+
 	e := echo.New()
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(middleware.BodyLimit(*bodyLimit))
 
 	e.Static("/", "frontend")
-	e.Static("/maps", "saved_maps")
+	e.Static("/maps/files", mapsDir)
 
 	e.POST("/save", saveMap)
-	e.GET("/load", loadMap)
+	// Older name of GET /maps
+	e.GET("/load", listMaps)
+	e.GET("/maps", listMaps)
+	e.POST("/maps", saveMap)
+	e.GET("/maps/:id", getMap)
+	e.PATCH("/maps/:id", updateMap)
+	e.DELETE("/maps/:id", deleteMap)
+	e.GET("/maps/:id/export", exportMap)
 	e.GET("/colors", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, tiles.TileColors)
+		set, err := lookupTileSet("tileSet", c.QueryParam("tileSet"))
+		if err != nil {
+			return generationError(c, err)
+		}
+		return c.JSON(http.StatusOK, set.Colors())
+	})
+	e.GET("/tilesets", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, tiles.All())
+	})
+	e.GET("/tilesets/:name", func(c echo.Context) error {
+		set, ok := tiles.Lookup(c.Param("name"))
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "Tile set not found")
+		}
+		return c.JSON(http.StatusOK, set)
 	})
+	e.GET("/methods", listMethods)
 	e.POST("/generate", generateTiles)
+	e.POST("/generate/stream", streamGenerate)
+	e.POST("/pipeline", generatePipeline)
+	e.POST("/render", renderGrid)
+	e.POST("/import", importImage)
+	e.POST("/jobs", submitJob)
+	e.GET("/jobs/:id", getJob)
+	e.DELETE("/jobs/:id", cancelJob)
+	e.POST("/mlca/learn", learnRules)
 
 	e.GET("/*", func(c echo.Context) error {
 		log.Printf("Requested file: %s", c.Request().URL.Path)
@@ -51,263 +125,984 @@ func main() {
 	}
 }
 
-func saveMap(c echo.Context) error {
-	type SaveRequest struct {
-		ImageData string `json:"imageData"`
+// loadTileSets registers the tile sets in dir; a missing dir holds none.
+func loadTileSets(dir string) error {
+	names, err := tiles.LoadDir(dir)
+	if len(names) > 0 {
+		log.Printf("Loaded tile sets %s from %s", strings.Join(names, ", "), dir)
+	}
+	return err
+}
+
+// lookupTileSet returns the tile set name selects, the default one for an
+// empty name, or a ValidationError for field.
+func lookupTileSet(field, name string) (*tiles.Set, error) {
+	set, ok := tiles.Lookup(name)
+	if !ok {
+		return nil, generator.ValidationError{tileSetError(field)}
 	}
+	return set, nil
+}
+
+func tileSetError(field string) generator.FieldError {
+	return generator.FieldError{Field: field, Message: "must be one of " + strings.Join(tiles.Names(), ", ")}
+}
+
+// mapsDir holds saved maps, their thumbnails and the map index
+const mapsDir = "saved_maps"
+
+// maps is the map library; opened in main
+var maps *mapstore.Store
 
+// SaveRequest holds the canvas rendering and, to make the map reloadable, its
+// grid and the request that generated it.
+type SaveRequest struct {
+	ImageData string             `json:"imageData"`
+	Grid      [][]int            `json:"grid,omitempty"`
+	Request   *generator.Request `json:"request,omitempty"`
+	// TileSet of Grid when there is no Request
+	TileSet string `json:"tileSet,omitempty"`
+	// Compress gzips the map file
+	Compress    bool     `json:"compress,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+func saveMap(c echo.Context) error {
 	req := new(SaveRequest)
 	if err := c.Bind(req); err != nil {
-		log.Printf("Invalid request format: %v", err)
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+		return generationError(c, generator.ValidationError{{Field: "body", Message: "invalid JSON request"}})
+	}
+	if req.ImageData == "" && len(req.Grid) == 0 {
+		return generationError(c, generator.ValidationError{{Field: "grid", Message: "nothing to save: send a grid or imageData"}})
 	}
 
-	dataURL := req.ImageData
-	comma := strings.IndexByte(dataURL, ',')
-	if comma >= 0 {
-		dataURL = dataURL[comma+1:]
+	var imgData []byte
+	if req.ImageData != "" {
+		dataURL := req.ImageData
+		comma := strings.IndexByte(dataURL, ',')
+		if comma >= 0 {
+			dataURL = dataURL[comma+1:]
+		}
+		var err error
+		if imgData, err = base64.StdEncoding.DecodeString(dataURL); err != nil {
+			return generationError(c, generator.ValidationError{{Field: "imageData", Message: "must be a base64 data URL"}})
+		}
+	}
+	var m *mapfile.Map
+	if len(req.Grid) > 0 {
+		var err error
+		if m, err = newMapFile(req); err != nil {
+			return generationError(c, err)
+		}
 	}
-	imgData, err := base64.StdEncoding.DecodeString(dataURL)
+
+	entry, err := maps.Create(m, imgData, req.Compress, mapstore.Meta{Name: req.Name, Tags: req.Tags, Description: req.Description})
 	if err != nil {
-		log.Printf("Invalid image data: %v", err)
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid image data")
+		log.Printf("Failed to save the map: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save the map")
 	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Map saved", "fileName": entry.Thumbnail, "map": entry})
+}
 
-	fileName := fmt.Sprintf("map-%d.png", time.Now().Unix())
-	if err = os.WriteFile("saved_maps/"+fileName, imgData, 0644); err != nil {
-		log.Printf("Failed to save the image: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save the image")
+// newMapFile builds the saved form of req.Grid. Unpainted cells (-1) are kept.
+func newMapFile(req *SaveRequest) (*mapfile.Map, error) {
+	grid, err := generator.GridFromInts(req.Grid)
+	if err != nil {
+		return nil, generator.ValidationError{{Field: "grid", Message: err.Error()}}
+	}
+	field, name := "tileSet", req.TileSet
+	if req.Request != nil {
+		field, name = "request.tileSet", req.Request.TileSet
 	}
+	set, err := lookupTileSet(field, name)
+	if err != nil {
+		return nil, err
+	}
+	for y, row := range req.Grid {
+		for x, v := range row {
+			if v != -1 && !set.Valid(v) {
+				return nil, generator.ValidationError{{Field: fmt.Sprintf("grid[%d][%d]", y, x),
+					Message: fmt.Sprintf("tile type must be -1 or between 0 and %d", set.Len()-1)}}
+			}
+		}
+	}
+	m := &mapfile.Map{
+		Version:   mapfile.Version,
+		Generator: mapfile.GeneratorVersion(),
+		Width:     grid.Width(),
+		Height:    grid.Height(),
+		Grid:      req.Grid,
+		TileSet:   set.Name,
+		Palette:   set.Colors(),
+		Request:   req.Request,
+		Metrics: mapfile.Metrics{
+			Entropy:     metrics.TileEntropy(req.Grid),
+			Frequencies: metrics.TileFrequencies(req.Grid, set.Len()),
+			FractalDim:  metrics.FractalDimension(req.Grid),
+			LandRatio:   metrics.LandRatio(req.Grid, set),
+		},
+	}
+	if req.Request != nil {
+		seed := req.Request.SeedOr(generator.DefaultSeed)
+		if req.Request.WFCSeed != nil {
+			seed = *req.Request.WFCSeed
+		}
+		m.Seed = &seed
+	}
+	return m, nil
+}
 
-	return c.JSON(http.StatusOK, map[string]string{"message": "Map saved", "fileName": fileName})
+// listMaps lists saved maps, newest first. Query parameters filter by method,
+// tag, q (name or description) and metric ranges written min..max, e.g.
+// landRatio=0.3..0.6 or entropy=..2.
+func listMaps(c echo.Context) error {
+	f := mapstore.Filter{
+		Method:  c.QueryParam("method"),
+		Tag:     c.QueryParam("tag"),
+		Query:   c.QueryParam("q"),
+		Metrics: map[string]mapstore.Range{},
+	}
+	for _, name := range []string{"entropy", "fractalDim", "landRatio"} {
+		s := c.QueryParam(name)
+		if s == "" {
+			continue
+		}
+		r, err := parseRange(s)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]any{"errors": generator.ValidationError{{Field: name, Message: err.Error()}}})
+		}
+		f.Metrics[name] = r
+	}
+	list := maps.List(f)
+	if list == nil {
+		list = []mapstore.Entry{}
+	}
+	return c.JSON(http.StatusOK, list)
 }
 
-func loadMap(c echo.Context) error {
-	var files []string
-	err := filepath.Walk("saved_maps", func(path string, info fs.FileInfo, err error) error {
-		if err == nil && !info.IsDir() {
-			files = append(files, filepath.Base(path))
+// parseRange parses "min..max" with optional ends; a single number is an
+// exact value.
+func parseRange(s string) (mapstore.Range, error) {
+	lo, hi, found := strings.Cut(s, "..")
+	if !found {
+		hi = lo
+	}
+	var r mapstore.Range
+	for _, b := range []struct {
+		s   string
+		dst **float64
+	}{{lo, &r.Min}, {hi, &r.Max}} {
+		if b.s == "" {
+			continue
 		}
-		return nil
-	})
+		v, err := strconv.ParseFloat(b.s, 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid range %q, want min..max", s)
+		}
+		*b.dst = &v
+	}
+	return r, nil
+}
+
+// MapDetail is a library entry with its saved map, if it has one.
+type MapDetail struct {
+	mapstore.Entry
+	Map *mapfile.Map `json:"map,omitempty"`
+}
+
+// getMap returns a saved map with its grid and request, to be loaded back
+// into the editor.
+func getMap(c echo.Context) error {
+	entry, m, err := maps.Read(c.Param("id"))
+	if err != nil {
+		return mapError(c, err)
+	}
+	return c.JSON(http.StatusOK, MapDetail{Entry: entry, Map: m})
+}
+
+// updateMap renames, tags or describes a saved map.
+func updateMap(c echo.Context) error {
+	var patch mapstore.Patch
+	if err := c.Bind(&patch); err != nil {
+		return generationError(c, generator.ValidationError{{Field: "body", Message: "invalid JSON request"}})
+	}
+	entry, err := maps.Update(c.Param("id"), patch)
+	if err != nil {
+		return mapError(c, err)
+	}
+	return c.JSON(http.StatusOK, entry)
+}
+
+func deleteMap(c echo.Context) error {
+	if err := maps.Delete(c.Param("id")); err != nil {
+		return mapError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// exportMap responds with a saved map in Tiled format (format=tmx or tmj).
+// By default the map comes zipped with a generated tilePx-sized color
+// tileset. With tileset set to an image path, the map refers to that image,
+// cut into tileWidth x tileHeight tiles (tileCount tiles in columns columns),
+// and mapping lists the tile index of every tile type.
+func exportMap(c echo.Context) error {
+	entry, m, err := maps.Read(c.Param("id"))
+	if err != nil {
+		return mapError(c, err)
+	}
+	if m == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Map has no grid")
+	}
+	format := c.QueryParam("format")
+	if format == "" {
+		format = tiled.FormatTMX
+	}
+	if !slices.Contains(tiled.Formats, format) {
+		return generationError(c, generator.ValidationError{{Field: "format", Message: "must be one of " + strings.Join(tiled.Formats, ", ")}})
+	}
+	ints := func(name string, def int) (int, error) {
+		s := c.QueryParam(name)
+		if s == "" {
+			return def, nil
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return 0, generator.ValidationError{{Field: name, Message: "must be a positive integer"}}
+		}
+		return v, nil
+	}
+
+	var ts tiled.Tileset
+	var tilesetImage []byte
+	if image := c.QueryParam("tileset"); image != "" {
+		ts = tiled.Tileset{Name: "tiles", Image: image}
+		for _, f := range []struct {
+			name string
+			dst  *int
+			def  int
+		}{
+			{"tileWidth", &ts.TileWidth, tiled.DefaultTileSize}, {"tileHeight", &ts.TileHeight, tiled.DefaultTileSize},
+			{"tileCount", &ts.TileCount, len(m.Palette)}, {"columns", &ts.Columns, len(m.Palette)},
+		} {
+			if *f.dst, err = ints(f.name, f.def); err != nil {
+				return generationError(c, err)
+			}
+		}
+		ts.ImageWidth = ts.Columns * ts.TileWidth
+		ts.ImageHeight = (ts.TileCount + ts.Columns - 1) / ts.Columns * ts.TileHeight
+		if s := c.QueryParam("mapping"); s != "" {
+			for _, part := range strings.Split(s, ",") {
+				v, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					return generationError(c, generator.ValidationError{{Field: "mapping", Message: "must list tile indexes, e.g. 0,1,2"}})
+				}
+				ts.Mapping = append(ts.Mapping, v)
+			}
+		}
+	} else {
+		size, err := ints("tilePx", tiled.DefaultTileSize)
+		if err != nil || size > render.MaxCellSize {
+			return generationError(c, generator.ValidationError{{Field: "tilePx", Message: fmt.Sprintf("must be between 1 and %d", render.MaxCellSize)}})
+		}
+		if ts, tilesetImage, err = tiled.ColorTileset(m.Palette, size); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	tm, err := tiled.New([]tiled.Layer{{Name: "terrain", Grid: m.Grid}}, ts)
+	if err != nil {
+		return generationError(c, generator.ValidationError{{Field: "tileset", Message: err.Error()}})
+	}
+	name := entry.ID
+	if tilesetImage == nil {
+		data, err := tm.Encode(format)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+"."+format))
+		contentType := echo.MIMEApplicationXMLCharsetUTF8
+		if format == tiled.FormatTMJ {
+			contentType = echo.MIMEApplicationJSON
+		}
+		return c.Blob(http.StatusOK, contentType, data)
+	}
+	data, err := tm.Zip(name, format, tilesetImage)
 	if err != nil {
-		log.Printf("Failed to load map images: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load map images")
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
-	return c.JSON(http.StatusOK, files)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".zip"))
+	return c.Blob(http.StatusOK, "application/zip", data)
 }
 
-type GenerateRequest struct {
-	GenerationMethod string  `json:"generationMethod"`
-	Width            int     `json:"width"`
-	Height           int     `json:"height"`
-	Iterations       int     `json:"iterations"`
-	RandomnessFactor float64 `json:"randomnessFactor"`
-	PrevGrid         [][]int `json:"prevGrid"`
-	PaintedTiles     [][]int `json:"paintedTiles"`
-	NoiseScale       float64 `json:"noiseScale"`
-	NoiseOctaves     int     `json:"noiseOctaves"`
-	NoisePersistence float64 `json:"noisePersistence"`
-	NoiseLacunarity  float64 `json:"noiseLacunarity"`
-	WFCSeed          *int64  `json:"wfcSeed,omitempty"`
+func mapError(c echo.Context, err error) error {
+	if errors.Is(err, mapstore.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Map not found")
+	}
+	log.Printf("Map library error: %v", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 }
 
 type GenerateResponse struct {
-	Grid        [][]int                    `json:"grid"`
-	Colors      [tiles.NumTileTypes]string `json:"colors"`
-	Entropy     float64                    `json:"entropy"`
-	Adjacency   map[int]map[int]int        `json:"adjacency"`
-	Frequencies map[int]float64            `json:"frequencies"`
-	Autocorr    map[string]float64         `json:"autocorr"`
-	FractalDim  float64                    `json:"fractalDim"`
-	Spectrum    [][]float64                `json:"spectrum"`
+	Grid        [][]int             `json:"grid"`
+	Colors      []string            `json:"colors"`
+	Entropy     float64             `json:"entropy"`
+	Adjacency   map[int]map[int]int `json:"adjacency"`
+	Frequencies map[int]float64     `json:"frequencies"`
+	Autocorr    map[string]float64  `json:"autocorr"`
+	FractalDim  float64             `json:"fractalDim"`
+	Spectrum    [][]float64         `json:"spectrum"`
+
+	// Method-specific statistics, e.g. MLCA iteration counts or cave regions
+	Stats any `json:"stats,omitempty"`
+	// Recorded frames when snapshotEvery is set
+	Timelapse *timelapse.Timelapse `json:"timelapse,omitempty"`
+	// Image is the grid rendered as a data URL when image is set
+	Image string `json:"image,omitempty"`
+}
+
+var (
+	// limits bounds /generate and /pipeline requests; set from flags
+	limits    = generator.DefaultLimits
+	maxStages = 16
+)
+
+// listMethods describes every registered generator and its parameters.
+func listMethods(c echo.Context) error {
+	all := generator.All()
+	infos := make([]generator.Info, len(all))
+	for i, g := range all {
+		infos[i] = generator.Describe(g)
+		infos[i].Params = limits.Apply(infos[i].Params)
+	}
+	return c.JSON(http.StatusOK, infos)
+}
+
+var errUnknownMethod = errors.New("unknown generation method")
+
+// lookupMethod returns the generator registered under req.GenerationMethod
+// after validating req against it.
+func lookupMethod(req *generator.Request) (generator.Generator, error) {
+	gen, ok := generator.Lookup(req.GenerationMethod)
+	if !ok {
+		return nil, fmt.Errorf("%w %q (available: %s)", errUnknownMethod, req.GenerationMethod, strings.Join(generator.Names(), ", "))
+	}
+	if err := generator.Validate(gen, req, limits); err != nil {
+		return nil, err
+	}
+	return gen, nil
+}
+
+// runMethod runs the generator registered under req.GenerationMethod. It
+// stops when ctx is canceled.
+func runMethod(ctx context.Context, req *generator.Request, progress generator.Progress) (*GenerateResponse, error) {
+	gen, err := lookupMethod(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := gen.Generate(ctx, req, progress)
+	if err != nil {
+		return nil, err
+	}
+	set := req.Tiles()
+	resp := newGenerateResponse(res.Grid.Ints(), set)
+	resp.Stats = res.Stats
+	if req.SnapshotEvery > 0 && len(res.Frames) > 0 {
+		frames := make([][][]int, len(res.Frames))
+		for i, f := range res.Frames {
+			frames[i] = f.Ints()
+			res.Frames[i] = nil
+		}
+		if resp.Timelapse, err = timelapse.Build(frames, req.SnapshotEvery, req.SnapshotEncoding, resp.Colors); err != nil {
+			return nil, err
+		}
+	}
+	if req.Image != "" {
+		img, err := render.Render(req.Image, resp.Grid, resp.Colors, render.Options{CellSize: req.ImageCellSize})
+		if err != nil {
+			return nil, err
+		}
+		resp.Image = "data:" + render.ContentType(req.Image) + ";base64," + base64.StdEncoding.EncodeToString(img)
+	}
+	return resp, nil
 }
 
 func generateTiles(c echo.Context) error {
-	req := new(GenerateRequest)
+	req := new(generator.Request)
 	if err := c.Bind(req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
-	}
-
-	var (
-		intGrid [][]int
-		ent     float64
-		adj     map[int]map[int]int
-		freq    map[int]float64
-		auto    map[[2]int]float64
-		fd      float64
-		spec    [][]float64
-
-		genErr error
-	)
-
-	switch req.GenerationMethod {
-	case "mlca":
-		intGrid, ent, adj, freq, auto, fd, spec, genErr = runMLCA(req)
-	case "noise":
-		intGrid, ent, adj, freq, auto, fd, spec, genErr = runNoise(req)
-	case "wfc":
-		intGrid, ent, adj, freq, auto, fd, spec, genErr = runWFC(req)
-	case "gol":
-		intGrid, ent, adj, freq, auto, fd, spec, genErr = runGOL(req)
-	default:
-		return echo.NewHTTPError(http.StatusBadRequest, "Unknown generation method")
+		return generationError(c, generator.ValidationError{{Field: "body", Message: "invalid JSON request"}})
 	}
 
+	// Stop generating when the client goes away
+	resp, genErr := runMethod(c.Request().Context(), req, generator.Progress{})
 	if genErr != nil {
-		log.Printf("Generation error: %v", genErr)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": genErr.Error()})
+		return generationError(c, genErr)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// generationError responds 400 with the list of field errors for invalid
+// requests, and 500 for failed generations.
+func generationError(c echo.Context, err error) error {
+	return c.JSON(errorBody(err))
+}
+
+// errorBody returns the status and body generationError responds with.
+func errorBody(err error) (int, any) {
+	var invalid generator.ValidationError
+	if errors.As(err, &invalid) {
+		return http.StatusBadRequest, map[string]any{"errors": invalid}
+	}
+	if errors.Is(err, errUnknownMethod) {
+		return http.StatusBadRequest, map[string]any{"errors": generator.ValidationError{
+			{Field: "generationMethod", Message: err.Error()},
+		}}
+	}
+	log.Printf("Generation error: %v", err)
+	return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+}
+
+const (
+	defaultStreamFPS = 10
+	maxStreamFPS     = 60
+	framesNone       = "none"
+)
+
+// StreamFrame is the payload of a "frame" event of /generate/stream. The first
+// frame holds the whole grid; with delta frames later ones hold the cells
+// changed since the previous frame. Partial grids, such as WFC frames with
+// undecided -1 cells, come without metrics.
+type StreamFrame struct {
+	Grid        [][]int           `json:"grid,omitempty"`
+	Deltas      []timelapse.Delta `json:"deltas,omitempty"`
+	Entropy     *float64          `json:"entropy,omitempty"`
+	Frequencies map[int]float64   `json:"frequencies,omitempty"`
+}
+
+// complete reports whether every cell of grid holds a tile.
+func complete(grid [][]int) bool {
+	for _, row := range grid {
+		if slices.Contains(row, -1) {
+			return false
+		}
+	}
+	return true
+}
+
+// streamGenerate runs a /generate request and streams it as Server-Sent
+// Events: "progress" and "frame" events at most fps times a second each, then
+// a "result" event with the GenerateResponse or an "error" event. The frames
+// query parameter selects delta (default), full or none.
+func streamGenerate(c echo.Context) error {
+	req := new(generator.Request)
+	if err := c.Bind(req); err != nil {
+		return generationError(c, generator.ValidationError{{Field: "body", Message: "invalid JSON request"}})
+	}
+	fps := defaultStreamFPS
+	if s := c.QueryParam("fps"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxStreamFPS {
+			return generationError(c, generator.ValidationError{{Field: "fps", Message: fmt.Sprintf("must be between 1 and %d", maxStreamFPS)}})
+		}
+		fps = n
+	}
+	frames := c.QueryParam("frames")
+	switch frames {
+	case "":
+		frames = timelapse.EncodingDelta
+	case timelapse.EncodingDelta, timelapse.EncodingFull, framesNone:
+	default:
+		return generationError(c, generator.ValidationError{{Field: "frames", Message: "must be one of delta, full, none"}})
+	}
+	if _, err := lookupMethod(req); err != nil {
+		return generationError(c, err)
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(event string, data any) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Stream event %s: %v", event, err)
+			return
+		}
+		// A failed write means the client left; the request context stops the generator
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		w.Flush()
+	}
+
+	interval := time.Second / time.Duration(fps)
+	var lastStep, lastFrame time.Time
+	var sent [][]int
+	numTypes := req.Tiles().Len()
+	progress := generator.Progress{
+		Step: func(done, total int) {
+			if now := time.Now(); now.Sub(lastStep) >= interval {
+				lastStep = now
+				send("progress", map[string]int{"done": done, "total": total})
+			}
+		},
+	}
+	if frames != framesNone {
+		progress.Frame = func(grid generator.Grid) {
+			now := time.Now()
+			if now.Sub(lastFrame) < interval {
+				return
+			}
+			lastFrame = now
+			cur := grid.Ints()
+			var frame StreamFrame
+			if complete(cur) {
+				entropy := metrics.TileEntropy(cur)
+				frame.Entropy, frame.Frequencies = &entropy, metrics.TileFrequencies(cur, numTypes)
+			}
+			if sent == nil || frames == timelapse.EncodingFull {
+				frame.Grid = cur
+			} else if frame.Deltas = timelapse.Deltas([][][]int{sent, cur})[0]; len(frame.Deltas) == 0 {
+				return
+			}
+			sent = cur
+			send("frame", frame)
+		}
 	}
 
+	resp, err := runMethod(c.Request().Context(), req, progress)
+	if err != nil {
+		_, body := errorBody(err)
+		send("error", body)
+		return nil
+	}
+	send("result", resp)
+	return nil
+}
+
+// newGenerateResponse computes all metrics for a grid of set.
+func newGenerateResponse(intGrid [][]int, set *tiles.Set) *GenerateResponse {
+	auto := metrics.Autocorrelation(intGrid, 5)
 	autoStr := make(map[string]float64, len(auto))
 	for k, v := range auto {
 		key := fmt.Sprintf("%d,%d", k[0], k[1])
 		autoStr[key] = v
 	}
 
-	resp := GenerateResponse{
+	return &GenerateResponse{
 		Grid:        intGrid,
-		Colors:      tiles.TileColors,
-		Entropy:     ent,
-		Adjacency:   adj,
-		Frequencies: freq,
+		Colors:      set.Colors(),
+		Entropy:     metrics.TileEntropy(intGrid),
+		Adjacency:   metrics.AdjacencyMatrix(intGrid),
+		Frequencies: metrics.TileFrequencies(intGrid, set.Len()),
 		Autocorr:    autoStr,
-		FractalDim:  fd,
-		Spectrum:    spec,
+		FractalDim:  metrics.FractalDimension(intGrid),
+		Spectrum:    metrics.SpectralSpectrum(intGrid),
 	}
-	return c.JSON(http.StatusOK, resp)
 }
 
-func runMLCA(req *GenerateRequest) ([][]int, float64, map[int]map[int]int, map[int]float64, map[[2]int]float64, float64, [][]float64, error) {
-	// Convert painted, ensuring correct dimensions for mlca.GenerateTiles
-	painted := make([][]tiles.TileType, req.Height)
-	for y := 0; y < req.Height; y++ {
-		painted[y] = make([]tiles.TileType, req.Width)
-		for x := 0; x < req.Width; x++ {
-			// Default to -1 (not painted)
-			painted[y][x] = -1
-			if y < len(req.PaintedTiles) && x < len(req.PaintedTiles[y]) {
-				if req.PaintedTiles[y][x] != -1 {
-					painted[y][x] = tiles.TileType(req.PaintedTiles[y][x])
-				}
-			}
+// RenderRequest is a grid to draw, e.g. from /generate or a saved map.
+type RenderRequest struct {
+	Grid [][]int `json:"grid"`
+	// Format is "png" (default) or "svg"
+	Format    string `json:"format,omitempty"`
+	CellSize  int    `json:"cellSize,omitempty"`
+	GridLines bool   `json:"gridLines,omitempty"`
+	GridColor string `json:"gridColor,omitempty"`
+	// TileSet selects the palette, which Colors overrides
+	TileSet string   `json:"tileSet,omitempty"`
+	Colors  []string `json:"colors,omitempty"`
+}
+
+// renderGrid responds with the grid drawn as a PNG or SVG image.
+func renderGrid(c echo.Context) error {
+	req := new(RenderRequest)
+	if err := c.Bind(req); err != nil {
+		return generationError(c, generator.ValidationError{{Field: "body", Message: "invalid JSON request"}})
+	}
+	var invalid generator.ValidationError
+	if req.Format == "" {
+		req.Format = render.FormatPNG
+	}
+	if !slices.Contains(render.Formats, req.Format) {
+		invalid = append(invalid, generator.FieldError{Field: "format", Message: "must be one of " + strings.Join(render.Formats, ", ")})
+	}
+	if req.CellSize < 0 || req.CellSize > render.MaxCellSize {
+		invalid = append(invalid, generator.FieldError{Field: "cellSize", Message: fmt.Sprintf("must be between 1 and %d", render.MaxCellSize)})
+	}
+	if _, err := generator.GridFromInts(req.Grid); err != nil {
+		invalid = append(invalid, generator.FieldError{Field: "grid", Message: err.Error()})
+	} else if len(req.Grid) == 0 || len(req.Grid[0]) == 0 {
+		invalid = append(invalid, generator.FieldError{Field: "grid", Message: "must not be empty"})
+	} else if len(req.Grid[0]) > limits.MaxWidth || len(req.Grid) > limits.MaxHeight {
+		invalid = append(invalid, generator.FieldError{Field: "grid", Message: fmt.Sprintf("must not exceed %dx%d cells", limits.MaxWidth, limits.MaxHeight)})
+	} else if len(req.Grid)*len(req.Grid[0]) > limits.MaxCells {
+		invalid = append(invalid, generator.FieldError{Field: "grid", Message: fmt.Sprintf("must not exceed %d cells", limits.MaxCells)})
+	} else if req.CellSize >= 0 && generator.ImagePixels(len(req.Grid[0]), len(req.Grid), req.CellSize) > limits.MaxImagePixels {
+		invalid = append(invalid, generator.FieldError{Field: "cellSize", Message: fmt.Sprintf("the image must not exceed %d pixels", limits.MaxImagePixels)})
+	}
+	if req.GridColor != "" {
+		if _, err := render.ParseHexColor(req.GridColor); err != nil {
+			invalid = append(invalid, generator.FieldError{Field: "gridColor", Message: err.Error()})
 		}
 	}
-
-	// Generate
-	tileGrid, err := mlca.GenerateTiles(req.Width, req.Height, painted, req.Iterations, req.RandomnessFactor, mlca.CreateDefaultRules(), rand.New(rand.NewSource(fixedSeed)))
+	set, ok := tiles.Lookup(req.TileSet)
+	if !ok {
+		invalid = append(invalid, tileSetError("tileSet"))
+	}
+	if len(invalid) > 0 {
+		return generationError(c, invalid)
+	}
+	colors := req.Colors
+	if len(colors) == 0 {
+		colors = set.Colors()
+	}
+	img, err := render.Render(req.Format, req.Grid, colors, render.Options{CellSize: req.CellSize, GridLines: req.GridLines, GridColor: req.GridColor})
 	if err != nil {
-		return nil, 0, nil, nil, nil, 0, nil, err
+		return generationError(c, generator.ValidationError{{Field: "colors", Message: err.Error()}})
 	}
-	intGrid := make([][]int, len(tileGrid))
-	for y := range tileGrid {
-		intGrid[y] = make([]int, len(tileGrid[y]))
-		for x := range tileGrid[y] {
-			intGrid[y][x] = int(tileGrid[y][x].Color)
+	return c.Blob(http.StatusOK, render.ContentType(req.Format), img)
+}
+
+// importImage turns a map image into a grid. The image is the multipart file
+// "image" or the raw request body; blockSize, tolerance and tileSet may be
+// given as form or query values.
+func importImage(c echo.Context) error {
+	// FormValue would consume a raw body sent as a urlencoded form
+	multipart := strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm)
+	param := func(name string) string {
+		if v := c.QueryParam(name); v != "" || !multipart {
+			return v
 		}
+		return c.FormValue(name)
 	}
-	// Metrics
-	ent := metrics.TileEntropy(intGrid)
-	adj := metrics.AdjacencyMatrix(intGrid)
-	freq := metrics.TileFrequencies(intGrid)
-	auto := metrics.Autocorrelation(intGrid, 5)
-	fd := metrics.FractalDimension(intGrid)
-	spec := metrics.SpectralSpectrum(intGrid)
 
-	return intGrid, ent, adj, freq, auto, fd, spec, nil
-}
+	opts := importer.Options{MaxPixels: limits.MaxImagePixels, MaxCells: limits.MaxCells, MaxWidth: limits.MaxWidth, MaxHeight: limits.MaxHeight}
+	var invalid generator.ValidationError
+	if s := param("blockSize"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			invalid = append(invalid, generator.FieldError{Field: "blockSize", Message: "must be a positive integer"})
+		}
+		opts.BlockSize = v
+	}
+	if s := param("tolerance"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 {
+			invalid = append(invalid, generator.FieldError{Field: "tolerance", Message: "must be a positive number"})
+		}
+		opts.Tolerance = v
+	}
+	set, ok := tiles.Lookup(param("tileSet"))
+	if !ok {
+		invalid = append(invalid, tileSetError("tileSet"))
+	}
+	if len(invalid) > 0 {
+		return generationError(c, invalid)
+	}
 
-func runNoise(req *GenerateRequest) ([][]int, float64, map[int]map[int]int, map[int]float64, map[[2]int]float64, float64, [][]float64, error) {
-	ng := noise.NewNoiseGenerator(int64(fixedSeed), req.NoiseScale, req.NoiseOctaves, req.NoisePersistence, req.NoiseLacunarity)
-	tileGrid := ng.Generate(req.Width, req.Height)
-	intGrid := make([][]int, req.Height)
-	for y := 0; y < req.Height; y++ {
-		intGrid[y] = make([]int, req.Width)
-		for x := 0; x < req.Width; x++ {
-			intGrid[y][x] = int(tileGrid[y][x].Color)
+	var r io.Reader = c.Request().Body
+	if multipart {
+		fh, err := c.FormFile("image")
+		if err != nil {
+			return generationError(c, generator.ValidationError{{Field: "image", Message: "is required"}})
 		}
+		f, err := fh.Open()
+		if err != nil {
+			return generationError(c, err)
+		}
+		defer f.Close()
+		r = f
 	}
-	ent := metrics.TileEntropy(intGrid)
-	adj := metrics.AdjacencyMatrix(intGrid)
-	freq := metrics.TileFrequencies(intGrid)
-	auto := metrics.Autocorrelation(intGrid, 5)
-	fd := metrics.FractalDimension(intGrid)
-	spec := metrics.SpectralSpectrum(intGrid)
+	res, err := importer.Decode(r, set.Colors(), opts)
+	if err != nil {
+		return generationError(c, generator.ValidationError{{Field: "image", Message: err.Error()}})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// PipelineRequest chains generators: each stage's grid is the next stage's
+// prevGrid. Stages inherit width, height, seed and tile set when they leave
+// them unset.
+type PipelineRequest struct {
+	Width   int                 `json:"width"`
+	Height  int                 `json:"height"`
+	Seed    *int64              `json:"seed,omitempty"`
+	TileSet string              `json:"tileSet,omitempty"`
+	Stages  []generator.Request `json:"stages"`
+	// StageMetrics adds the grid and metrics of every stage to the response
+	StageMetrics bool `json:"stageMetrics,omitempty"`
+}
 
-	return intGrid, ent, adj, freq, auto, fd, spec, nil
+// PipelineResponse holds the final grid and metrics, and optionally every stage's.
+type PipelineResponse struct {
+	*GenerateResponse
+	Stages []*GenerateResponse `json:"stages,omitempty"`
 }
 
-func runWFC(req *GenerateRequest) ([][]int, float64, map[int]map[int]int, map[int]float64, map[[2]int]float64, float64, [][]float64, error) {
-	gridObj := wfc.NewGrid(req.Width, req.Height)
+func generatePipeline(c echo.Context) error {
+	req := new(PipelineRequest)
+	if err := c.Bind(req); err != nil {
+		return generationError(c, generator.ValidationError{{Field: "body", Message: "invalid JSON request"}})
+	}
+	resp, err := runPipeline(c.Request().Context(), req)
+	if err != nil {
+		return generationError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
 
-	currentWFCSeed := int64(defaultWFCSeed)
-	if req.WFCSeed != nil {
-		currentWFCSeed = *req.WFCSeed
+func runPipeline(ctx context.Context, req *PipelineRequest) (*PipelineResponse, error) {
+	if len(req.Stages) == 0 || len(req.Stages) > maxStages {
+		return nil, generator.ValidationError{{Field: "stages", Message: fmt.Sprintf("must hold between 1 and %d stages", maxStages)}}
+	}
+	resp := &PipelineResponse{}
+	var grid [][]int
+	for i := range req.Stages {
+		stage := req.Stages[i]
+		if stage.Width == 0 {
+			stage.Width = req.Width
+		}
+		if stage.Height == 0 {
+			stage.Height = req.Height
+		}
+		if stage.Seed == nil {
+			stage.Seed = req.Seed
+		}
+		if stage.TileSet == "" {
+			stage.TileSet = req.TileSet
+		}
+		if i > 0 {
+			if stage.Width != len(grid[0]) || stage.Height != len(grid) {
+				return nil, generator.ValidationError{{Field: fmt.Sprintf("stages[%d].width", i), Message: "size differs from the previous stage"}}
+			}
+			stage.PrevGrid = grid
+		}
+		out, err := runMethod(ctx, &stage, generator.Progress{})
+		var invalid generator.ValidationError
+		if errors.As(err, &invalid) {
+			return nil, invalid.Prefix(fmt.Sprintf("stages[%d].", i))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stage %d (%s): %w", i, stage.GenerationMethod, err)
+		}
+		grid = out.Grid
+		resp.GenerateResponse = out
+		if req.StageMetrics {
+			resp.Stages = append(resp.Stages, out)
+		}
 	}
+	return resp, nil
+}
 
-	tilesOut, err := gridObj.Solve(100, currentWFCSeed)
+// jobManager runs /jobs generations in the background; set up in main
+var jobManager *jobs.Manager
 
+// submitJob validates a generation request like /generate and queues it,
+// responding 202 with the job id.
+func submitJob(c echo.Context) error {
+	req := new(generator.Request)
+	if err := c.Bind(req); err != nil {
+		return generationError(c, generator.ValidationError{{Field: "body", Message: "invalid JSON request"}})
+	}
+	if _, err := lookupMethod(req); err != nil {
+		return generationError(c, err)
+	}
+	id, err := jobManager.Submit(func(ctx context.Context, progress func(done, total int)) (any, error) {
+		return runMethod(ctx, req, generator.Progress{Step: progress})
+	})
+	if errors.Is(err, jobs.ErrQueueFull) {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	}
 	if err != nil {
-		return nil, 0, nil, nil, nil, 0, nil, err
+		return generationError(c, err)
 	}
+	c.Response().Header().Set(echo.HeaderLocation, "/jobs/"+id)
+	return c.JSON(http.StatusAccepted, map[string]string{"id": id})
+}
 
-	intGrid := make([][]int, len(tilesOut))
-	for y := range tilesOut {
-		intGrid[y] = make([]int, len(tilesOut[y]))
-		for x := range tilesOut[y] {
-			intGrid[y][x] = int(tilesOut[y][x])
-		}
+// getJob returns the status, progress and, once done, the GenerateResponse of
+// a job.
+func getJob(c echo.Context) error {
+	snap, err := jobManager.Get(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
 	}
-	ent := metrics.TileEntropy(intGrid)
-	adj := metrics.AdjacencyMatrix(intGrid)
-	freq := metrics.TileFrequencies(intGrid)
-	auto := metrics.Autocorrelation(intGrid, 5)
-	fd := metrics.FractalDimension(intGrid)
-	spec := metrics.SpectralSpectrum(intGrid)
+	return c.JSON(http.StatusOK, snap)
+}
+
+// cancelJob stops a queued or running job.
+func cancelJob(c echo.Context) error {
+	snap, err := jobManager.Cancel(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, snap)
+}
+
+type LearnRequest struct {
+	Examples           [][][]int             `json:"examples"`
+	TileSet            string                `json:"tileSet,omitempty"`
+	MaxRules           int                   `json:"maxRules,omitempty"`
+	MinSupport         int                   `json:"minSupport,omitempty"`
+	MinConfidence      float64               `json:"minConfidence,omitempty"`
+	Neighborhood       string                `json:"neighborhood,omitempty"`
+	NeighborhoodRadius int                   `json:"neighborhoodRadius,omitempty"`
+	NeighborhoodKernel []neighborhood.Offset `json:"neighborhoodKernel,omitempty"`
+}
 
-	return intGrid, ent, adj, freq, auto, fd, spec, nil
+// LearnResponse can be merged into a GenerateRequest as is.
+type LearnResponse struct {
+	Rules         []mlca.TerrainRule `json:"rules"`
+	RuleSelection string             `json:"ruleSelection"`
 }
 
-func runGOL(req *GenerateRequest) ([][]int, float64, map[int]map[int]int, map[int]float64, map[[2]int]float64, float64, [][]float64, error) {
-	var tileGrid [][]gol.Tile
-	if len(req.PrevGrid) > 0 {
-		tileGrid = make([][]gol.Tile, req.Height)
-		for y := 0; y < req.Height; y++ {
-			tileGrid[y] = make([]gol.Tile, req.Width)
-			for x := 0; x < req.Width; x++ {
-				tileGrid[y][x].State = tiles.TileType(req.PrevGrid[y][x])
+func learnRules(c echo.Context) error {
+	req := new(LearnRequest)
+	if err := c.Bind(req); err != nil {
+		return generationError(c, generator.ValidationError{{Field: "body", Message: "invalid JSON request"}})
+	}
+	resp, err := runLearn(req)
+	var invalid generator.ValidationError
+	if err != nil && !errors.As(err, &invalid) {
+		// The examples passed ValidateLearn, so what is left is what rules they support
+		err = generator.ValidationError{{Field: "examples", Message: err.Error()}}
+	}
+	if err != nil {
+		return generationError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// runLearn learns rules after checking req against the limits of /generate.
+func runLearn(req *LearnRequest) (*LearnResponse, error) {
+	set, err := lookupTileSet("tileSet", req.TileSet)
+	if err != nil {
+		return nil, err
+	}
+	if err := generator.ValidateLearn(req.Examples, req.MaxRules, req.NeighborhoodRadius, req.NeighborhoodKernel, set, limits); err != nil {
+		return nil, err
+	}
+	nbh, err := neighborhood.FromSpec(req.Neighborhood, req.NeighborhoodRadius, req.NeighborhoodKernel)
+	if err != nil {
+		return nil, err
+	}
+	examples := make([][][]tiles.TileType, len(req.Examples))
+	for i, grid := range req.Examples {
+		examples[i] = make([][]tiles.TileType, len(grid))
+		for y, row := range grid {
+			examples[i][y] = make([]tiles.TileType, len(row))
+			for x, v := range row {
+				examples[i][y][x] = tiles.TileType(v)
 			}
 		}
-	} else {
-		tileGrid = gol.NewGrid(req.Width, req.Height)
-	}
-	// Paint overrides
-	for y := range req.PaintedTiles {
-		for x := range req.PaintedTiles[y] {
-			v := req.PaintedTiles[y][x]
-			if v < 0 || v >= int(tiles.NumTileTypes) {
-				continue
-			}
-			t := tiles.TileType(v)
-			switch t {
-			case tiles.Bushes, tiles.Sand:
-				tileGrid[y][x].State = t
-			default:
-				log.Printf("Ignoring painted tile %d at %d,%d", v, x, y)
-			}
+	}
+	rules, err := mlca.LearnRules(examples, mlca.LearnOptions{
+		Tiles:         set,
+		Neighborhood:  nbh,
+		MaxRules:      req.MaxRules,
+		MinSupport:    req.MinSupport,
+		MinConfidence: req.MinConfidence,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &LearnResponse{Rules: rules, RuleSelection: string(mlca.SelectPriority)}, nil
+}
+
+// runLearnCommand implements "learn [flags] example.json...". Example files hold
+// a grid as a plain array, as {"grid": ...} or as a batch_generator result, or
+// are map images, which are imported like /import does.
+func runLearnCommand(args []string) error {
+	flags := flag.NewFlagSet("learn", flag.ExitOnError)
+	out := flags.String("o", "", "write the rule set to this file instead of stdout")
+	req := &LearnRequest{}
+	flags.IntVar(&req.MaxRules, "max-rules", 0, "maximum number of rules (default 24)")
+	flags.IntVar(&req.MinSupport, "min-support", 0, "minimum example cells per rule (default 5)")
+	flags.Float64Var(&req.MinConfidence, "min-confidence", 0, "minimum rule confidence (default 0.6)")
+	flags.StringVar(&req.Neighborhood, "neighborhood", "", "moore or vonneumann")
+	flags.IntVar(&req.NeighborhoodRadius, "radius", 1, "neighborhood radius")
+	flags.StringVar(&req.TileSet, "tile-set", "", "tile set of the examples (default terrain)")
+	tileSetDir := flags.String("tilesets", "tilesets", "directory of tile set JSON files to load")
+	imageOpts := importer.Options{MaxPixels: limits.MaxImagePixels, MaxCells: limits.MaxCells}
+	flags.IntVar(&imageOpts.BlockSize, "block-size", importer.DefaultBlockSize, "pixels per tile of image examples")
+	flags.Float64Var(&imageOpts.Tolerance, "tolerance", importer.DefaultTolerance, "largest color distance of image examples")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("usage: learn [flags] example.json|example.png...")
+	}
+	if err := loadTileSets(*tileSetDir); err != nil {
+		return err
+	}
+	set, err := lookupTileSet("tile-set", req.TileSet)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range flags.Args() {
+		grid, err := loadExampleGrid(path, set, imageOpts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
 		}
+		req.Examples = append(req.Examples, grid)
 	}
-	next, err := gol.StepCA(tileGrid, req.Iterations)
+	resp, err := runLearn(req)
 	if err != nil {
-		return nil, 0, nil, nil, nil, 0, nil, err
+		return err
+	}
+	data, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = fmt.Println(string(data))
+		return err
+	}
+	log.Printf("Learned %d rules from %d examples", len(resp.Rules), len(req.Examples))
+	return os.WriteFile(*out, data, 0644)
+}
+
+func loadExampleGrid(path string, set *tiles.Set, imageOpts importer.Options) ([][]int, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".gif", ".jpg", ".jpeg":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		res, err := importer.Decode(f, set.Colors(), imageOpts)
+		if err != nil {
+			return nil, err
+		}
+		if res.UnmatchedCells > 0 {
+			return nil, fmt.Errorf("%d cells match no tile color (%d pixels), try a larger -tolerance", res.UnmatchedCells, res.UnmatchedPixels)
+		}
+		return res.Grid, nil
 	}
-	intGrid := gol.TilesToIntGrid(next)
-	ent := metrics.TileEntropy(intGrid)
-	adj := metrics.AdjacencyMatrix(intGrid)
-	freq := metrics.TileFrequencies(intGrid)
-	auto := metrics.Autocorrelation(intGrid, 5)
-	fd := metrics.FractalDimension(intGrid)
-	spec := metrics.SpectralSpectrum(intGrid)
 
-	return intGrid, ent, adj, freq, auto, fd, spec, nil
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var grid [][]int
+	if err := json.Unmarshal(data, &grid); err == nil {
+		return grid, nil
+	}
+	var wrapped struct {
+		Grid            [][]int `json:"grid"`
+		ResponseMetrics struct {
+			Grid [][]int `json:"grid"`
+		} `json:"responseMetrics"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, err
+	}
+	if len(wrapped.Grid) > 0 {
+		return wrapped.Grid, nil
+	}
+	if len(wrapped.ResponseMetrics.Grid) > 0 {
+		return wrapped.ResponseMetrics.Grid, nil
+	}
+	return nil, errors.New("no grid found")
 }
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/generator"
+
+	"github.com/labstack/echo/v4"
+)
+
+// intp returns a pointer to n.
+func intp(n int) *int { return &n }
+
+func TestRunPipeline(t *testing.T) {
+	seed := int64(5)
+	req := &PipelineRequest{
+		Width: 16, Height: 12, Seed: &seed, StageMetrics: true,
+		Stages: []generator.Request{
+			{GenerationMethod: "noise", NoiseScale: 2, NoiseOctaves: intp(3)},
+			{GenerationMethod: "mlca", Iterations: intp(3)},
+		},
+	}
+	resp, err := runPipeline(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Stages) != 2 {
+		t.Fatalf("got %d stages, want 2", len(resp.Stages))
+	}
+	if !reflect.DeepEqual(resp.Grid, resp.Stages[1].Grid) {
+		t.Error("the final grid is not the last stage's")
+	}
+	for i, s := range resp.Stages {
+		if len(s.Grid) != 12 || len(s.Grid[0]) != 16 {
+			t.Errorf("stage %d is %dx%d, want the pipeline's 16x12", i, len(s.Grid[0]), len(s.Grid))
+		}
+	}
+
+	// Without stage metrics only the final grid is returned
+	req.StageMetrics = false
+	again, err := runPipeline(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Stages != nil || !reflect.DeepEqual(again.Grid, resp.Grid) {
+		t.Error("the same seeded pipeline gave a different result")
+	}
+}
+
+func TestRunPipelineErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		stages []generator.Request
+		field  string
+	}{
+		{"no stages", nil, "stages"},
+		{"too many stages", make([]generator.Request, maxStages+1), "stages"},
+		{"size change", []generator.Request{
+			{GenerationMethod: "noise"},
+			{GenerationMethod: "mlca", Width: 8},
+		}, "stages[1].width"},
+		{"invalid stage", []generator.Request{
+			{GenerationMethod: "noise"},
+			{GenerationMethod: "mlca", Iterations: intp(-1)},
+		}, "stages[1].iterations"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runPipeline(context.Background(), &PipelineRequest{Width: 16, Height: 12, Stages: tt.stages})
+			var invalid generator.ValidationError
+			if !errors.As(err, &invalid) || invalid[0].Field != tt.field {
+				t.Errorf("runPipeline() = %v, want an error on %s", err, tt.field)
+			}
+		})
+	}
+}
+
+// event is one Server-Sent Event of /generate/stream.
+type event struct {
+	name string
+	data json.RawMessage
+}
+
+// stream posts body to /generate/stream with query and returns the response
+// status and its events. A canceled ctx stops the generator at once.
+func stream(t *testing.T, ctx context.Context, query, body string) (int, []event) {
+	t.Helper()
+	e := echo.New()
+	e.POST("/generate/stream", streamGenerate)
+	req := httptest.NewRequest(http.MethodPost, "/generate/stream?"+query, strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var events []event
+	var name string
+	scanner := bufio.NewScanner(rec.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			name = v
+		} else if v, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			events = append(events, event{name, json.RawMessage(v)})
+		}
+	}
+	return rec.Code, events
+}
+
+// named returns the events called name.
+func named(events []event, name string) []event {
+	var out []event
+	for _, e := range events {
+		if e.name == name {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestStreamGenerate(t *testing.T) {
+	const wfc = `{"generationMethod": "wfc", "width": 12, "height": 10, "seed": 2}`
+	const mlca = `{"generationMethod": "mlca", "width": 12, "height": 10, "seed": 2, "iterations": 40}`
+	tests := []struct {
+		name, query, body string
+		// frames is the exact number of frame events, -1 for at least two
+		frames int
+	}{
+		{"delta", "fps=60", wfc, -1},
+		{"full", "fps=60&frames=full", wfc, -1},
+		{"none", "fps=60&frames=none", wfc, 0},
+		// A run shorter than a second gets only the first frame at one fps
+		{"throttled", "fps=1", mlca, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, events := stream(t, context.Background(), tt.query, tt.body)
+			if code != http.StatusOK || len(events) == 0 {
+				t.Fatalf("status %d with %d events", code, len(events))
+			}
+			last := events[len(events)-1]
+			if last.name != "result" {
+				t.Fatalf("last event is %s: %s", last.name, last.data)
+			}
+			var resp GenerateResponse
+			if err := json.Unmarshal(last.data, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Grid) != 10 || len(resp.Grid[0]) != 12 || resp.Frequencies == nil {
+				t.Errorf("result holds a %dx%d grid and frequencies %v", len(resp.Grid[0]), len(resp.Grid), resp.Frequencies)
+			}
+
+			frames := named(events, "frame")
+			if tt.frames >= 0 && len(frames) != tt.frames || tt.frames < 0 && len(frames) < 2 {
+				t.Fatalf("got %d frames, want %d", len(frames), tt.frames)
+			}
+			if tt.name == "throttled" && len(named(events, "progress")) > 1 {
+				t.Errorf("got %d progress events at one fps", len(named(events, "progress")))
+			}
+			var grid [][]int
+			for i, e := range frames {
+				var f StreamFrame
+				if err := json.Unmarshal(e.data, &f); err != nil {
+					t.Fatal(err)
+				}
+				whole := i == 0 || strings.Contains(tt.query, "frames=full")
+				if whole != (f.Grid != nil) || (!whole && len(f.Deltas) == 0) {
+					t.Fatalf("frame %d has grid %t and %d deltas", i, f.Grid != nil, len(f.Deltas))
+				}
+				if f.Grid != nil {
+					grid = f.Grid
+				}
+				for _, d := range f.Deltas {
+					grid[d[1]][d[0]] = d[2]
+				}
+				// Metrics only describe grids without undecided cells
+				if partial := !complete(grid); partial != (f.Entropy == nil) || partial != (f.Frequencies == nil) {
+					t.Errorf("frame %d: partial %t with entropy %v, frequencies %v", i, partial, f.Entropy, f.Frequencies)
+				}
+				if _, ok := f.Frequencies[-1]; ok {
+					t.Errorf("frame %d counts undecided cells as a tile", i)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamGenerateErrors(t *testing.T) {
+	const body = `{"generationMethod": "mlca", "width": 12, "height": 10, "iterations": 5}`
+	tests := []struct {
+		name, query, body string
+		field             string
+	}{
+		{"fps", "fps=0", body, "fps"},
+		{"frames", "frames=gif", body, "frames"},
+		{"request", "", `{"generationMethod": "mlca", "width": 0, "height": 10}`, "width"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := stream(t, context.Background(), tt.query, tt.body)
+			if code != http.StatusBadRequest {
+				t.Errorf("status %d, want a 400 before the stream starts", code)
+			}
+		})
+	}
+
+	// Once the headers are out, failures end the stream with an error event
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	code, events := stream(t, ctx, "", body)
+	if code != http.StatusOK || len(events) == 0 || events[len(events)-1].name != "error" {
+		t.Fatalf("status %d with events %v, want an error event", code, events)
+	}
+	if len(named(events, "result")) != 0 {
+		t.Error("a failed stream sent a result")
+	}
+}
+
+// fieldErrors calls handler with body and returns the status and the fields
+// of the structured error response.
+func fieldErrors(t *testing.T, handler echo.HandlerFunc, body string) (int, []string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	if err := handler(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("handler returned %v instead of responding", err)
+	}
+	var resp struct {
+		Errors generator.ValidationError `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response %s: %v", rec.Body, err)
+	}
+	var fields []string
+	for _, e := range resp.Errors {
+		fields = append(fields, e.Field)
+	}
+	return rec.Code, fields
+}
+
+func TestFieldErrors(t *testing.T) {
+	wide := `{"grid": [[` + strings.Repeat("0,", limits.MaxWidth) + `0]]}`
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		body    string
+		fields  []string
+	}{
+		{"generate body", generateTiles, `{`, []string{"body"}},
+		{"learn body", learnRules, `{`, []string{"body"}},
+		{"learn examples", learnRules, `{"examples": []}`, []string{"examples"}},
+		{"save body", saveMap, `{`, []string{"body"}},
+		{"save nothing", saveMap, `{}`, []string{"grid"}},
+		{"save tile", saveMap, `{"grid": [[0, 9]]}`, []string{"grid[0][1]"}},
+		{"save tile set", saveMap, `{"grid": [[0]], "tileSet": "moon"}`, []string{"tileSet"}},
+		{"render width", renderGrid, wide, []string{"grid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, fields := fieldErrors(t, tt.handler, tt.body)
+			if code != http.StatusBadRequest || !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("status %d with fields %v, want 400 with %v", code, fields, tt.fields)
+			}
+		})
+	}
+}
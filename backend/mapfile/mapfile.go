@@ -0,0 +1,136 @@
+// Package mapfile reads and writes saved maps: the tile grid together with
+// everything needed to regenerate or edit it later.
+package mapfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"procedural-map-generation-toolkit/backend/generator"
+)
+
+// Version is the current save format version.
+const Version = 1
+
+// Extensions of saved map files; gzip-compressed files end in ExtGzip.
+const (
+	Ext     = ".json"
+	ExtGzip = ".json.gz"
+)
+
+// Metrics is the summary of the grid stored with a map.
+type Metrics struct {
+	Entropy     float64         `json:"entropy"`
+	Frequencies map[int]float64 `json:"frequencies"`
+	FractalDim  float64         `json:"fractalDim"`
+	LandRatio   float64         `json:"landRatio"`
+}
+
+// Value returns the scalar metric with the given JSON name.
+func (m Metrics) Value(name string) (float64, bool) {
+	switch name {
+	case "entropy":
+		return m.Entropy, true
+	case "fractalDim":
+		return m.FractalDim, true
+	case "landRatio":
+		return m.LandRatio, true
+	}
+	return 0, false
+}
+
+// Map is a saved map.
+type Map struct {
+	Version int `json:"version"`
+	// Generator identifies the toolkit build that saved the map
+	Generator string    `json:"generator"`
+	Created   time.Time `json:"created"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	Grid      [][]int   `json:"grid"`
+	// TileSet names the tile set of Grid; empty is the default set
+	TileSet string `json:"tileSet,omitempty"`
+	// Palette holds the color of every tile type used by Grid
+	Palette []string `json:"palette"`
+	Seed    *int64   `json:"seed,omitempty"`
+	// Request is the generation request that produced Grid, if any
+	Request *generator.Request `json:"request,omitempty"`
+	Metrics Metrics            `json:"metrics"`
+	// Thumbnail is the file name of the PNG rendering saved next to the map
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// Write saves m to path, gzip-compressed when compress is set.
+func Write(path string, m *Map, compress bool) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Read loads a map saved by Write, compressed or not.
+func Read(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// gzip magic number
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	m := new(Map)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Version == 0 || m.Version > Version {
+		return nil, fmt.Errorf("unsupported map version %d", m.Version)
+	}
+	if len(m.Grid) == 0 {
+		return nil, errors.New("map has no grid")
+	}
+	return m, nil
+}
+
+// GeneratorVersion returns the module version, or the VCS revision for
+// development builds.
+func GeneratorVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	if version == "" || version == "(devel)" {
+		version = "devel"
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				version += "+" + s.Value
+			}
+		}
+	}
+	return info.Main.Path + " " + version
+}
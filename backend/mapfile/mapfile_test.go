@@ -0,0 +1,77 @@
+package mapfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"procedural-map-generation-toolkit/backend/generator"
+)
+
+func TestRoundTrip(t *testing.T) {
+	seed := int64(42)
+	m := &Map{
+		Version:   Version,
+		Generator: GeneratorVersion(),
+		Created:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Width:     3,
+		Height:    2,
+		Grid:      [][]int{{0, 1, 2}, {5, 6, 7}},
+		TileSet:   "terrain",
+		Palette:   []string{"#000000", "#111111"},
+		Seed:      &seed,
+		Request:   &generator.Request{GenerationMethod: "noise", Width: 3, Height: 2, Seed: &seed},
+		Metrics:   Metrics{Entropy: 1.5, Frequencies: map[int]float64{0: 0.5, 1: 0.5}, LandRatio: 0.25},
+		Thumbnail: "map.png",
+	}
+	for _, compress := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "map"+Ext)
+		if err := Write(path, m, compress); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("compress=%t: read %+v, want %+v", compress, got, m)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name, data, err string
+	}{
+		{"no version", `{"grid": [[0]]}`, "unsupported map version 0"},
+		{"future version", `{"version": 99, "grid": [[0]]}`, "unsupported map version 99"},
+		{"no grid", `{"version": 1}`, "no grid"},
+		{"not json", `grid`, "invalid character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "map.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Read(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Read() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMetricsValue(t *testing.T) {
+	m := Metrics{Entropy: 1, FractalDim: 2, LandRatio: 3}
+	for name, want := range map[string]float64{"entropy": 1, "fractalDim": 2, "landRatio": 3} {
+		if got, ok := m.Value(name); !ok || got != want {
+			t.Errorf("Value(%q) = %g, %t, want %g", name, got, ok, want)
+		}
+	}
+	if _, ok := m.Value("frequencies"); ok {
+		t.Error(`Value("frequencies") found a scalar`)
+	}
+}
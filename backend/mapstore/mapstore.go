@@ -0,0 +1,390 @@
+// Package mapstore keeps saved maps in a directory with a JSON index holding
+// their ids, names, tags and metrics.
+package mapstore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"procedural-map-generation-toolkit/backend/mapfile"
+)
+
+// IndexFile is the name of the index inside the store directory.
+const IndexFile = "index.json"
+
+// ErrNotFound is returned for unknown map ids.
+var ErrNotFound = errors.New("map not found")
+
+// Entry describes a stored map. Maps saved as a PNG only have no File, size
+// or metrics.
+type Entry struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Tags        []string  `json:"tags"`
+	Description string    `json:"description,omitempty"`
+	Created     time.Time `json:"created"`
+	// File and Thumbnail are file names inside the store directory
+	File             string           `json:"file,omitempty"`
+	Thumbnail        string           `json:"thumbnail,omitempty"`
+	Width            int              `json:"width,omitempty"`
+	Height           int              `json:"height,omitempty"`
+	GenerationMethod string           `json:"generationMethod,omitempty"`
+	Seed             *int64           `json:"seed,omitempty"`
+	Metrics          *mapfile.Metrics `json:"metrics,omitempty"`
+}
+
+// Meta is the user-editable part of an entry.
+type Meta struct {
+	Name        string
+	Tags        []string
+	Description string
+}
+
+// Patch changes the fields that are not nil.
+type Patch struct {
+	Name        *string   `json:"name"`
+	Tags        *[]string `json:"tags"`
+	Description *string   `json:"description"`
+}
+
+// Store is a directory of maps. It is safe for concurrent use.
+type Store struct {
+	dir     string
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+// Open loads the index of dir. Without an index, the map files and images
+// already in dir are indexed under their file names.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, entries: map[string]*Entry{}}
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := s.scan(); err != nil {
+			return nil, err
+		}
+		return s, s.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		s.entries[e.ID] = e
+	}
+	return s, nil
+}
+
+// scan indexes the files of a store directory that has no index yet.
+func (s *Store) scan() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	images := map[string]time.Time{}
+	for _, f := range files {
+		name := f.Name()
+		info, err := f.Info()
+		if f.IsDir() || err != nil || name == IndexFile {
+			continue
+		}
+		id, ok := trimMapExt(name)
+		if !ok {
+			images[name] = info.ModTime()
+			continue
+		}
+		m, err := mapfile.Read(filepath.Join(s.dir, name))
+		if err != nil {
+			continue
+		}
+		e := &Entry{ID: id, Name: id, Tags: []string{}, File: name}
+		e.fill(m)
+		s.entries[id] = e
+	}
+	for _, e := range s.entries {
+		delete(images, e.Thumbnail)
+	}
+	for name, mod := range images {
+		id := strings.TrimSuffix(name, filepath.Ext(name))
+		if _, dup := s.entries[id]; dup {
+			id = name
+		}
+		s.entries[id] = &Entry{ID: id, Name: id, Tags: []string{}, Created: mod.UTC(), Thumbnail: name}
+	}
+	return nil
+}
+
+func trimMapExt(name string) (string, bool) {
+	for _, ext := range []string{mapfile.ExtGzip, mapfile.Ext} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext), true
+		}
+	}
+	return "", false
+}
+
+// fill copies the searchable fields of m.
+func (e *Entry) fill(m *mapfile.Map) {
+	e.Created = m.Created
+	e.Thumbnail = m.Thumbnail
+	e.Width, e.Height = m.Width, m.Height
+	e.Seed = m.Seed
+	metrics := m.Metrics
+	e.Metrics = &metrics
+	if m.Request != nil {
+		e.GenerationMethod = m.Request.GenerationMethod
+	}
+}
+
+// save writes the index; s.mu must be held.
+func (s *Store) save() error {
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Replace the index atomically so a crash cannot truncate it
+	tmp := filepath.Join(s.dir, IndexFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, IndexFile))
+}
+
+// Create stores m with its PNG thumbnail under a new id. Either may be nil.
+func (s *Store) Create(m *mapfile.Map, thumbnail []byte, compress bool, meta Meta) (Entry, error) {
+	if m == nil && thumbnail == nil {
+		return Entry{}, errors.New("nothing to save")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, err := s.newID()
+	if err != nil {
+		return Entry{}, err
+	}
+	e := &Entry{ID: id, Name: meta.Name, Tags: normalizeTags(meta.Tags), Description: meta.Description, Created: time.Now().UTC()}
+	if e.Name == "" {
+		e.Name = id
+	}
+	if thumbnail != nil {
+		e.Thumbnail = id + ".png"
+		if err := os.WriteFile(filepath.Join(s.dir, e.Thumbnail), thumbnail, 0644); err != nil {
+			return Entry{}, err
+		}
+	}
+	if m != nil {
+		m.Created = e.Created
+		m.Thumbnail = e.Thumbnail
+		e.File = id + mapfile.Ext
+		if compress {
+			e.File = id + mapfile.ExtGzip
+		}
+		if err := mapfile.Write(filepath.Join(s.dir, e.File), m, compress); err != nil {
+			s.remove(e)
+			return Entry{}, err
+		}
+		e.fill(m)
+	}
+	s.entries[id] = e
+	if err := s.save(); err != nil {
+		delete(s.entries, id)
+		s.remove(e)
+		return Entry{}, err
+	}
+	return e.clone(), nil
+}
+
+// newID returns an unused random id; s.mu must be held.
+func (s *Store) newID() (string, error) {
+	b := make([]byte, 6)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		id := hex.EncodeToString(b)
+		if _, dup := s.entries[id]; !dup {
+			return id, nil
+		}
+	}
+}
+
+// Get returns the entry of id.
+func (s *Store) Get(id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return e.clone(), nil
+}
+
+// Read returns the entry and the saved map of id. The map is nil for PNG-only
+// entries.
+func (s *Store) Read(id string) (Entry, *mapfile.Map, error) {
+	e, err := s.Get(id)
+	if err != nil || e.File == "" {
+		return e, nil, err
+	}
+	m, err := mapfile.Read(filepath.Join(s.dir, e.File))
+	return e, m, err
+}
+
+// Update applies p to the entry of id.
+func (s *Store) Update(id string, p Patch) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	old := *e
+	if p.Name != nil {
+		if strings.TrimSpace(*p.Name) == "" {
+			return Entry{}, errors.New("name must not be empty")
+		}
+		e.Name = *p.Name
+	}
+	if p.Tags != nil {
+		e.Tags = normalizeTags(*p.Tags)
+	}
+	if p.Description != nil {
+		e.Description = *p.Description
+	}
+	if err := s.save(); err != nil {
+		*e = old
+		return Entry{}, err
+	}
+	return e.clone(), nil
+}
+
+// Delete removes the entry of id and its files.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.entries, id)
+	if err := s.save(); err != nil {
+		s.entries[id] = e
+		return err
+	}
+	s.remove(e)
+	return nil
+}
+
+// remove deletes the files of e, ignoring missing ones.
+func (s *Store) remove(e *Entry) {
+	for _, name := range []string{e.File, e.Thumbnail} {
+		if name != "" {
+			os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+}
+
+// Range bounds a metric; nil ends are open.
+type Range struct {
+	Min, Max *float64
+}
+
+func (r Range) contains(v float64) bool {
+	return (r.Min == nil || v >= *r.Min) && (r.Max == nil || v <= *r.Max)
+}
+
+// Filter selects entries in List. Zero fields match everything.
+type Filter struct {
+	Method string
+	Tag    string
+	// Query matches names and descriptions, ignoring case
+	Query string
+	// Metrics bounds metrics by their JSON name, e.g. "landRatio"; entries
+	// without metrics never match a bound
+	Metrics map[string]Range
+}
+
+// List returns the entries matching f, newest first.
+func (s *Store) List(f Filter) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := strings.ToLower(f.Query)
+	var out []Entry
+	for _, e := range s.entries {
+		if f.Method != "" && e.GenerationMethod != f.Method {
+			continue
+		}
+		if f.Tag != "" && !slices.Contains(e.Tags, strings.ToLower(f.Tag)) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(e.Name), query) &&
+			!strings.Contains(strings.ToLower(e.Description), query) {
+			continue
+		}
+		if !e.matchMetrics(f.Metrics) {
+			continue
+		}
+		out = append(out, e.clone())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Created.Equal(out[j].Created) {
+			return out[i].Created.After(out[j].Created)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func (e *Entry) matchMetrics(bounds map[string]Range) bool {
+	if len(bounds) == 0 {
+		return true
+	}
+	if e.Metrics == nil {
+		return false
+	}
+	for name, r := range bounds {
+		v, ok := e.Metrics.Value(name)
+		if !ok || !r.contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Entry) clone() Entry {
+	c := *e
+	c.Tags = slices.Clone(e.Tags)
+	return c
+}
+
+// normalizeTags lower-cases, trims and de-duplicates tags.
+func normalizeTags(tags []string) []string {
+	out := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
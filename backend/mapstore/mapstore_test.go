@@ -0,0 +1,131 @@
+package mapstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/generator"
+	"procedural-map-generation-toolkit/backend/mapfile"
+)
+
+func newMap(method string, landRatio float64) *mapfile.Map {
+	return &mapfile.Map{
+		Version: mapfile.Version,
+		Width:   2,
+		Height:  1,
+		Grid:    [][]int{{0, 4}},
+		Request: &generator.Request{GenerationMethod: method},
+		Metrics: mapfile.Metrics{LandRatio: landRatio},
+	}
+}
+
+func TestCRUD(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.Create(newMap("noise", 0.5), []byte("png"), true, Meta{Tags: []string{" Island", "island", ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != e.ID || !reflect.DeepEqual(e.Tags, []string{"island"}) || e.GenerationMethod != "noise" {
+		t.Errorf("created %+v", e)
+	}
+	if _, m, err := s.Read(e.ID); err != nil || m.Thumbnail != e.Thumbnail || m.Grid[0][1] != 4 {
+		t.Errorf("Read() = %+v, %v", m, err)
+	}
+
+	name := "Archipelago"
+	if _, err := s.Update(e.ID, Patch{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	blank := " "
+	if _, err := s.Update(e.ID, Patch{Name: &blank}); err == nil {
+		t.Error("Update() with a blank name succeeded")
+	}
+	if _, err := s.Update("missing", Patch{Name: &name}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() of a missing map = %v, want ErrNotFound", err)
+	}
+
+	// The index survives reopening
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get(e.ID); err != nil || got.Name != name {
+		t.Errorf("after reopening Get() = %+v, %v", got, err)
+	}
+
+	if err := s.Delete(e.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(e.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete = %v, want ErrNotFound", err)
+	}
+	for _, name := range []string{e.File, e.Thumbnail} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s left after Delete: %v", name, err)
+		}
+	}
+	if _, err := s.Create(nil, nil, false, Meta{}); err == nil {
+		t.Error("Create() of nothing succeeded")
+	}
+}
+
+func TestList(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(m *mapfile.Map, meta Meta) string {
+		t.Helper()
+		e, err := s.Create(m, nil, false, meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e.ID
+	}
+	island := create(newMap("noise", 0.3), Meta{Name: "Island", Tags: []string{"coast"}})
+	cave := create(newMap("caves", 0.9), Meta{Name: "Deep cave", Description: "Dark tunnels"})
+	thumb, err := s.Create(nil, []byte("png"), false, Meta{Name: "Sketch", Tags: []string{"coast"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{island, cave, thumb.ID}},
+		{"method", Filter{Method: "caves"}, []string{cave}},
+		{"tag", Filter{Tag: "COAST"}, []string{island, thumb.ID}},
+		{"name", Filter{Query: "isl"}, []string{island}},
+		{"description", Filter{Query: "tunnels"}, []string{cave}},
+		{"metric", Filter{Metrics: map[string]Range{"landRatio": {Min: p(0.5)}}}, []string{cave}},
+		{"metric range", Filter{Metrics: map[string]Range{"landRatio": {Min: p(0.1), Max: p(0.3)}}}, []string{island}},
+		{"unknown metric", Filter{Metrics: map[string]Range{"size": {}}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range s.List(tt.filter) {
+				got = append(got, e.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("List() = %v, want %v", got, tt.want)
+			}
+			for _, id := range tt.want {
+				if !slices.Contains(got, id) {
+					t.Errorf("List() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package metrics
 
-// TileFrequencies returns relative frequency per type
-func TileFrequencies(grid [][]int) map[int]float64 {
+import "procedural-map-generation-toolkit/backend/tiles"
+
+// TileFrequencies returns relative frequency per type, with an entry for each
+// of the numTypes tile types of the grid's set
+func TileFrequencies(grid [][]int, numTypes int) map[int]float64 {
 	counts := make(map[int]int)
 	total := 0
 	for y := range grid {
@@ -11,9 +14,9 @@ func TileFrequencies(grid [][]int) map[int]float64 {
 		}
 	}
 
-	freq := make(map[int]float64, 8)
+	freq := make(map[int]float64, numTypes)
 
-	for t := 0; t < 8; t++ {
+	for t := 0; t < numTypes; t++ {
 		freq[t] = 0.0
 	}
 
@@ -22,3 +25,20 @@ func TileFrequencies(grid [][]int) map[int]float64 {
 	}
 	return freq
 }
+
+// LandRatio returns the share of land cells of set, see tiles.Set.IsLand. It
+// is the one definition of land shared by the map library and analyze_data.
+func LandRatio(grid [][]int, set *tiles.Set) float64 {
+	return LandRatioFromFrequencies(TileFrequencies(grid, set.Len()), set)
+}
+
+// LandRatioFromFrequencies is LandRatio for the result of TileFrequencies.
+func LandRatioFromFrequencies(freq map[int]float64, set *tiles.Set) float64 {
+	ratio := 0.0
+	for t, f := range freq {
+		if set.IsLand(t) {
+			ratio += f
+		}
+	}
+	return ratio
+}
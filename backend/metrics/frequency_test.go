@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func TestLandRatio(t *testing.T) {
+	const (
+		deepWater    = int(tiles.DeepWater)
+		water        = int(tiles.Water)
+		coastalWater = int(tiles.CoastalWater)
+		wetSand      = int(tiles.WetSand)
+		sand         = int(tiles.Sand)
+		grass        = int(tiles.Grass)
+		forest       = int(tiles.Forest)
+	)
+	caves := &tiles.Set{Name: "caves", Tiles: []tiles.Tile{
+		{ID: 0, Name: "Wall", Color: "#000000", Category: "wall"},
+		{ID: 1, Name: "Floor", Color: "#ffffff", Category: "floor"},
+		{ID: 2, Name: "Lake", Color: "#0000ff", Category: tiles.CategoryWater},
+	}}
+	tests := []struct {
+		name string
+		grid [][]int
+		set  *tiles.Set
+		want float64
+	}{
+		{"all water", [][]int{{deepWater, water, coastalWater}}, tiles.Default, 0},
+		// Wet sand is shore, not water
+		{"shore is land", [][]int{{water, wetSand}}, tiles.Default, 0.5},
+		{"mixed", [][]int{{water, sand}, {grass, forest}}, tiles.Default, 0.75},
+		{"unpainted", [][]int{{-1, sand}, {water, 99}}, tiles.Default, 0.25},
+		{"other set", [][]int{{0, 1, 2, 2}}, caves, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LandRatio(tt.grid, tt.set); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("LandRatio() = %g, want %g", got, tt.want)
+			}
+			freq := TileFrequencies(tt.grid, tt.set.Len())
+			if got := LandRatioFromFrequencies(freq, tt.set); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("LandRatioFromFrequencies() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTileFrequencies(t *testing.T) {
+	freq := TileFrequencies([][]int{{0, 0, 1, 3}}, 4)
+	want := map[int]float64{0: 0.5, 1: 0.25, 2: 0, 3: 0.25}
+	if len(freq) != len(want) {
+		t.Fatalf("TileFrequencies() = %v, want %v", freq, want)
+	}
+	for k, v := range want {
+		if got, ok := freq[k]; !ok || got != v {
+			t.Errorf("frequency of %d = %g, want %g", k, got, v)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package mlca
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// LearnOptions controls rule induction in LearnRules.
+type LearnOptions struct {
+	// Tiles is the tile set of the examples; nil is tiles.Default.
+	Tiles *tiles.Set
+	// Neighborhood defaults to the 8-cell Moore neighborhood when nil.
+	Neighborhood neighborhood.Neighborhood
+	// MaxRules limits the size of the learned rule set (default 24).
+	MaxRules int
+	// MinSupport is the minimum number of example cells backing a rule (default 5).
+	MinSupport int
+	// MinConfidence is how much more typical the rule's count range must be
+	// of target than of source cells, as a share in [0.5, 1] (default 0.6).
+	MinConfidence float64
+}
+
+// neighborGroup is a set of tile types counted together in a rule condition.
+type neighborGroup []tiles.TileType
+
+// learnGroups returns the neighbor groups rules are learned for: the water and
+// other tiles of set, as in CreateDefaultRules, plus every single tile type.
+func learnGroups(set *tiles.Set) []neighborGroup {
+	var groups []neighborGroup
+	for _, g := range [][]tiles.TileType{set.OfCategory(tiles.CategoryWater), set.Land()} {
+		if len(g) > 0 && len(g) < set.Len() {
+			groups = append(groups, g)
+		}
+	}
+	for t := tiles.TileType(0); int(t) < set.Len(); t++ {
+		groups = append(groups, neighborGroup{t})
+	}
+	return groups
+}
+
+type scoredRule struct {
+	rule  TerrainRule
+	score float64
+}
+
+// LearnRules estimates transition rules from example maps. For every tile
+// type and neighbor group it builds a histogram of neighbor counts. A rule
+// Source→Target is emitted for the count range that is far more typical of
+// Target cells than of Source cells in the examples; its probability is that
+// confidence. Rules are ordered and prioritized by how much evidence backs them.
+func LearnRules(examples [][][]tiles.TileType, opts LearnOptions) ([]TerrainRule, error) {
+	if len(examples) == 0 {
+		return nil, errors.New("no example grids given")
+	}
+	nbh := opts.Neighborhood
+	if len(nbh) == 0 {
+		nbh = neighborhood.Default
+	}
+	if opts.MaxRules <= 0 {
+		opts.MaxRules = 24
+	}
+	if opts.MinSupport <= 0 {
+		opts.MinSupport = 5
+	}
+	if opts.MinConfidence <= 0 {
+		opts.MinConfidence = 0.6
+	}
+
+	set := opts.Tiles
+	if set == nil {
+		set = tiles.Default
+	}
+	groups := learnGroups(set)
+	size := nbh.Size()
+	// hist[tile][group][count] = number of example cells
+	hist := make([][][]int, set.Len())
+	for t := range hist {
+		hist[t] = make([][]int, len(groups))
+		for g := range groups {
+			hist[t][g] = make([]int, size+1)
+		}
+	}
+
+	learnOpts := Options{Boundary: neighborhood.BoundaryFixed, BoundaryTile: set.Default("boundaryTile", tiles.DeepWater)}
+	for _, example := range examples {
+		if len(example) == 0 || len(example[0]) == 0 {
+			return nil, errors.New("empty example grid")
+		}
+		height, width := len(example), len(example[0])
+		grid := make([][]Tile, height)
+		for y := range example {
+			if len(example[y]) != width {
+				return nil, errors.New("example grid rows differ in length")
+			}
+			grid[y] = make([]Tile, width)
+			for x, t := range example[y] {
+				if !set.Valid(int(t)) {
+					return nil, errors.New("example grid contains an unknown tile type")
+				}
+				grid[y][x] = Tile{Color: t}
+			}
+		}
+		var neighbors []Tile
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				neighbors = gatherNeighbors(neighbors[:0], grid, x, y, width, height, nbh, learnOpts)
+				for g, group := range groups {
+					hist[grid[y][x].Color][g][CountTilesByType(neighbors, group...)]++
+				}
+			}
+		}
+	}
+
+	var candidates []scoredRule
+	for source := tiles.TileType(0); int(source) < set.Len(); source++ {
+		for target := tiles.TileType(0); int(target) < set.Len(); target++ {
+			if source == target {
+				continue
+			}
+			best := scoredRule{score: -1}
+			for g, group := range groups {
+				if r, ok := fitRange(hist[source][g], hist[target][g], opts); ok && r.score > best.score {
+					r.rule.SourceColor = source
+					r.rule.TargetColor = target
+					r.rule.NeighborTypes = group
+					if r.rule.MaxCount == size {
+						r.rule.MaxCount = -1
+					}
+					best = r
+				}
+			}
+			if best.score > 0 {
+				candidates = append(candidates, best)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	if len(candidates) > opts.MaxRules {
+		candidates = candidates[:opts.MaxRules]
+	}
+	rules := make([]TerrainRule, len(candidates))
+	for i, c := range candidates {
+		c.rule.Priority = len(candidates) - i
+		rules[i] = c.rule
+	}
+	return rules, nil
+}
+
+// fitRange finds the contiguous count range that is characteristic of the
+// target rather than the source type. Counts are compared as shares of each
+// type's cells so that a common type does not dominate every range.
+func fitRange(source, target []int, opts LearnOptions) (scoredRule, bool) {
+	totalSrc, totalTgt := sum(source), sum(target)
+	if totalTgt == 0 {
+		return scoredRule{}, false
+	}
+	best := scoredRule{}
+	found := false
+	for lo := range target {
+		var src, tgt int
+		for hi := lo; hi < len(target); hi++ {
+			src += source[hi]
+			tgt += target[hi]
+			if tgt < opts.MinSupport {
+				continue
+			}
+			pTgt := float64(tgt) / float64(totalTgt)
+			pSrc := 0.0
+			if totalSrc > 0 {
+				pSrc = float64(src) / float64(totalSrc)
+			}
+			confidence := pTgt / (pTgt + pSrc)
+			if confidence < opts.MinConfidence {
+				continue
+			}
+			score := float64(tgt) * (confidence - 0.5)
+			if score > best.score {
+				minCount := lo
+				if minCount == 0 {
+					minCount = -1
+				}
+				probability := math.Round(confidence*100) / 100
+				best = scoredRule{
+					rule:  TerrainRule{MinCount: minCount, MaxCount: hi, Probability: &probability},
+					score: score,
+				}
+				found = true
+			}
+		}
+	}
+	return best, found
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
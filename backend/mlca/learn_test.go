@@ -0,0 +1,106 @@
+package mlca
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// split returns a size x size grid with left on the left half and right on
+// the right half.
+func split(size int, left, right tiles.TileType) [][]tiles.TileType {
+	grid := make([][]tiles.TileType, size)
+	for y := range grid {
+		grid[y] = make([]tiles.TileType, size)
+		for x := range grid[y] {
+			grid[y][x] = left
+			if x >= size/2 {
+				grid[y][x] = right
+			}
+		}
+	}
+	return grid
+}
+
+func TestLearnRulesRecoversBorder(t *testing.T) {
+	rules, err := LearnRules([][][]tiles.TileType{split(16, tiles.Water, tiles.Sand)}, LearnOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sand with mostly water around it is water, water with little water
+	// around it is sand
+	want := []TerrainRule{
+		{SourceColor: tiles.Sand, TargetColor: tiles.Water, NeighborTypes: []tiles.TileType{tiles.Water}, MinCount: 4, MaxCount: -1},
+		{SourceColor: tiles.Water, TargetColor: tiles.Sand, NeighborTypes: tiles.Default.OfCategory(tiles.CategoryWater), MinCount: -1, MaxCount: 3},
+	}
+	for _, w := range want {
+		found := false
+		for _, r := range rules {
+			if r.SourceColor == w.SourceColor && r.TargetColor == w.TargetColor {
+				found = true
+				r.Probability, r.Priority = nil, 0
+				if !reflect.DeepEqual(r, w) {
+					t.Errorf("learned %+v, want %+v", r, w)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no rule from %d to %d learned", w.SourceColor, w.TargetColor)
+		}
+	}
+}
+
+func TestLearnRulesValid(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	examples := make([][][]tiles.TileType, 3)
+	for i := range examples {
+		examples[i] = split(24, tiles.TileType(rng.Intn(int(tiles.NumTileTypes))), tiles.TileType(rng.Intn(int(tiles.NumTileTypes))))
+		for n := 0; n < 100; n++ {
+			examples[i][rng.Intn(24)][rng.Intn(24)] = tiles.TileType(rng.Intn(int(tiles.NumTileTypes)))
+		}
+	}
+	for _, maxRules := range []int{1, 5, 24} {
+		rules, err := LearnRules(examples, LearnOptions{MaxRules: maxRules})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rules) == 0 || len(rules) > maxRules {
+			t.Errorf("MaxRules %d: learned %d rules", maxRules, len(rules))
+		}
+		for i, r := range rules {
+			if !tiles.Default.Valid(int(r.SourceColor)) || !tiles.Default.Valid(int(r.TargetColor)) || r.SourceColor == r.TargetColor {
+				t.Errorf("rule %d: invalid source %d or target %d", i, r.SourceColor, r.TargetColor)
+			}
+			if r.Probability == nil || *r.Probability < 0.6 || *r.Probability > 1 {
+				t.Errorf("rule %d: probability %v outside [0.6, 1]", i, r.Probability)
+			}
+			if r.Priority != len(rules)-i {
+				t.Errorf("rule %d: priority %d, want %d", i, r.Priority, len(rules)-i)
+			}
+			if r.MaxCount != -1 && r.MinCount > r.MaxCount {
+				t.Errorf("rule %d: empty count range %d..%d", i, r.MinCount, r.MaxCount)
+			}
+		}
+	}
+}
+
+func TestLearnRulesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		examples [][][]tiles.TileType
+	}{
+		{"no examples", nil},
+		{"empty grid", [][][]tiles.TileType{{}}},
+		{"ragged", [][][]tiles.TileType{{{0, 1}, {0}}}},
+		{"unknown tile", [][][]tiles.TileType{{{0, tiles.NumTileTypes}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LearnRules(tt.examples, LearnOptions{}); err == nil {
+				t.Error("LearnRules() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package mlca
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"math"
 	"math/rand"
+	"procedural-map-generation-toolkit/backend/neighborhood"
+	"procedural-map-generation-toolkit/backend/parallel"
 	"procedural-map-generation-toolkit/backend/tiles"
 )
 
@@ -13,18 +18,108 @@ type Tile struct {
 
 type ColorConditionFunc func(t Tile, neighbors []Tile, x, y int, grid [][]Tile, randomness float64) bool
 type TerrainRule struct {
-	SourceColor, TargetColor tiles.TileType
-	NeighborTypes            []tiles.TileType
-	MinCount, MaxCount       int
+	SourceColor   tiles.TileType   `json:"source"`
+	TargetColor   tiles.TileType   `json:"target"`
+	NeighborTypes []tiles.TileType `json:"neighborTypes"`
+	MinCount      int              `json:"min"`
+	MaxCount      int              `json:"max"`
+	// Probability is the chance that the rule fires once its condition holds.
+	// nil means the rule always fires, 0 disables it.
+	Probability *float64 `json:"probability,omitempty"`
+	// Priority orders matching rules under SelectPriority (higher wins).
+	Priority int `json:"priority,omitempty"`
 }
 
 func (r TerrainRule) Condition(t Tile, neighbors []Tile, _ int, _ int, _ [][]Tile, randomness float64, rng *rand.Rand) bool {
+	if t.Color != r.SourceColor {
+		return false
+	}
 	count := CountTilesByType(neighbors, r.NeighborTypes...)
-	passCount := (r.MinCount < 0 || count >= r.MinCount) && (r.MaxCount < 0 || count <= r.MaxCount)
-	if randomness <= 0 {
-		return t.Color == r.SourceColor && passCount
+	if (r.MinCount >= 0 && count < r.MinCount) || (r.MaxCount >= 0 && count > r.MaxCount) {
+		return false
+	}
+	if randomness > 0 && rng.Float64() >= randomness {
+		return false
+	}
+	if r.Probability == nil || *r.Probability >= 1 {
+		return true
 	}
-	return t.Color == r.SourceColor && passCount && rng.Float64() < randomness
+	return rng.Float64() < *r.Probability
+}
+
+// RuleSelection decides which rule is applied when several rules match a tile.
+type RuleSelection string
+
+const (
+	SelectFirst    RuleSelection = "first"    // first matching rule in list order
+	SelectWeighted RuleSelection = "weighted" // random among matches; Condition already weighs them by probability
+	SelectPriority RuleSelection = "priority" // highest priority, ties broken by list order
+)
+
+// ParseRuleSelection maps a request value to a RuleSelection. Empty means SelectFirst.
+func ParseRuleSelection(s string) (RuleSelection, error) {
+	switch RuleSelection(s) {
+	case "", SelectFirst:
+		return SelectFirst, nil
+	case SelectWeighted, SelectPriority:
+		return RuleSelection(s), nil
+	}
+	return "", fmt.Errorf("unknown rule selection %q", s)
+}
+
+// Options holds optional settings for GenerateTiles. The zero value reproduces
+// the original behavior.
+type Options struct {
+	Selection RuleSelection
+	// Tiles is the tile set random cells are drawn from; nil is tiles.Default.
+	Tiles *tiles.Set
+	// Neighborhood defaults to the 8-cell Moore neighborhood when nil.
+	Neighborhood neighborhood.Neighborhood
+	// Boundary defaults to BoundaryFixed; fixed neighbors use BoundaryTile.
+	Boundary     neighborhood.Boundary
+	BoundaryTile tiles.TileType
+	// StopOnConvergence ends the run once no cell changes or the grid
+	// oscillates with period 2.
+	StopOnConvergence bool
+	// MinChangeFraction ends the run once fewer than this fraction of cells
+	// change in an iteration. Zero disables the check.
+	MinChangeFraction float64
+	// SnapshotEvery records the grid every k iterations into Stats.Frames,
+	// together with the initial and final grid. Zero disables recording.
+	SnapshotEvery int
+	// Initial is an existing grid to continue from instead of random tiles.
+	// Painted tiles still override it.
+	Initial [][]tiles.TileType
+	// Locked marks cells the rules may not change. Locked cells still count
+	// as neighbors for the cells around them.
+	Locked [][]bool
+	// Workers is the number of goroutines stepping row bands; zero uses all
+	// CPUs. Results for a given seed do not depend on it.
+	Workers int
+	// Progress, when set, is called after every iteration with the number of
+	// iterations run and requested.
+	Progress func(done, total int)
+	// Watch, when set, receives the grid after every iteration. The grid is
+	// reused by the next iteration and must not be kept.
+	Watch func(grid [][]Tile)
+}
+
+// StopReason tells why GenerateTiles ended.
+type StopReason string
+
+const (
+	StopCompleted   StopReason = "completed"   // all iterations ran
+	StopStable      StopReason = "stable"      // no cell changed
+	StopOscillating StopReason = "oscillating" // grid equals the grid two iterations back
+	StopThreshold   StopReason = "threshold"   // changed fraction fell below MinChangeFraction
+)
+
+// Stats describes a GenerateTiles run.
+type Stats struct {
+	Iterations int        `json:"iterations"` // iterations actually run
+	StopReason StopReason `json:"stopReason"`
+	Changes    []int      `json:"changes"` // changed cells per iteration
+	Frames     [][][]Tile `json:"-"`       // recorded grids, see Options.SnapshotEvery
 }
 
 func CreateDefaultRules() []TerrainRule {
@@ -37,45 +132,45 @@ func CreateDefaultRules() []TerrainRule {
 
 	// Convert foliage adjacent to water into sand
 	coastalCleanup := []TerrainRule{
-		{tiles.Forest, tiles.Sand, waterTypes, 1, -1},
-		{tiles.Bushes, tiles.Sand, waterTypes, 1, -1},
+		{tiles.Forest, tiles.Sand, waterTypes, 1, -1, nil, 0},
+		{tiles.Bushes, tiles.Sand, waterTypes, 1, -1, nil, 0},
 	}
 
 	// Convert grass into sand and sand into wet sand adjacent to water
 	beachRules := []TerrainRule{
-		{tiles.Grass, tiles.Sand, waterTypes, 1, -1},
-		{tiles.Sand, tiles.WetSand, waterTypes, 2, -1},
+		{tiles.Grass, tiles.Sand, waterTypes, 1, -1, nil, 0},
+		{tiles.Sand, tiles.WetSand, waterTypes, 2, -1, nil, 0},
 	}
 
 	// Terrain transitions (erosion and sediment buildup)
 	terrainRules := []TerrainRule{
 		// Downgrade toward water
-		{tiles.WetSand, tiles.CoastalWater, landTypes, -1, 4},
-		{tiles.CoastalWater, tiles.Water, landTypes, -1, 2},
-		{tiles.Water, tiles.DeepWater, landTypes, -1, 1},
+		{tiles.WetSand, tiles.CoastalWater, landTypes, -1, 4, nil, 0},
+		{tiles.CoastalWater, tiles.Water, landTypes, -1, 2, nil, 0},
+		{tiles.Water, tiles.DeepWater, landTypes, -1, 1, nil, 0},
 		// Upgrade away from water
-		{tiles.DeepWater, tiles.Water, landTypes, 1, -1},
-		{tiles.Water, tiles.CoastalWater, landTypes, 2, -1},
-		{tiles.CoastalWater, tiles.WetSand, landTypes, 5, -1},
-		{tiles.WetSand, tiles.Sand, landTypes, 6, -1},
-		{tiles.Sand, tiles.Grass, landTypes, 7, -1},
+		{tiles.DeepWater, tiles.Water, landTypes, 1, -1, nil, 0},
+		{tiles.Water, tiles.CoastalWater, landTypes, 2, -1, nil, 0},
+		{tiles.CoastalWater, tiles.WetSand, landTypes, 5, -1, nil, 0},
+		{tiles.WetSand, tiles.Sand, landTypes, 6, -1, nil, 0},
+		{tiles.Sand, tiles.Grass, landTypes, 7, -1, nil, 0},
 	}
 
 	// Vegetation transitions
 	foliageRules := []TerrainRule{
 		// **Birth**
-		{tiles.Grass, tiles.Bushes, grass, 8, 8},
-		{tiles.Grass, tiles.Bushes, bushes, 2, 7},
-		{tiles.Bushes, tiles.Forest, bushes, 8, 8},
-		{tiles.Bushes, tiles.Forest, forest, 3, 6},
+		{tiles.Grass, tiles.Bushes, grass, 8, 8, nil, 0},
+		{tiles.Grass, tiles.Bushes, bushes, 2, 7, nil, 0},
+		{tiles.Bushes, tiles.Forest, bushes, 8, 8, nil, 0},
+		{tiles.Bushes, tiles.Forest, forest, 3, 6, nil, 0},
 		// **Survival**
-		{tiles.Bushes, tiles.Bushes, bushes, 2, 7},
-		{tiles.Forest, tiles.Forest, forest, 3, 6},
+		{tiles.Bushes, tiles.Bushes, bushes, 2, 7, nil, 0},
+		{tiles.Forest, tiles.Forest, forest, 3, 6, nil, 0},
 		// **Dying**
-		{tiles.Bushes, tiles.Grass, bushes, -1, 1},
-		{tiles.Bushes, tiles.Grass, bushes, 8, 8},
-		{tiles.Forest, tiles.Bushes, forest, -1, 2},
-		{tiles.Forest, tiles.Bushes, forest, 7, 8},
+		{tiles.Bushes, tiles.Grass, bushes, -1, 1, nil, 0},
+		{tiles.Bushes, tiles.Grass, bushes, 8, 8, nil, 0},
+		{tiles.Forest, tiles.Bushes, forest, -1, 2, nil, 0},
+		{tiles.Forest, tiles.Bushes, forest, 7, 8, nil, 0},
 	}
 
 	// Combine in order: coastal cleanup → beaches → terrain → vegetation
@@ -85,6 +180,29 @@ func CreateDefaultRules() []TerrainRule {
 	return rules
 }
 
+// ScaleRules rescales neighbor counts written for the 8-cell Moore neighborhood
+// to a neighborhood of the given size, so the default rules keep their meaning
+// (e.g. "all neighbors") on larger or smaller neighborhoods.
+func ScaleRules(rules []TerrainRule, size int) []TerrainRule {
+	const baseSize = 8
+	if size == baseSize {
+		return rules
+	}
+	scale := func(count int) int {
+		if count < 0 {
+			return count
+		}
+		return int(math.Round(float64(count) * float64(size) / baseSize))
+	}
+	scaled := make([]TerrainRule, len(rules))
+	for i, r := range rules {
+		r.MinCount = scale(r.MinCount)
+		r.MaxCount = scale(r.MaxCount)
+		scaled[i] = r
+	}
+	return scaled
+}
+
 func CountTilesByType(neighbors []Tile, types ...tiles.TileType) int {
 	count := 0
 	for _, neighbor := range neighbors {
@@ -98,29 +216,153 @@ func CountTilesByType(neighbors []Tile, types ...tiles.TileType) int {
 	return count
 }
 
-func GenerateTiles(width, height int, paintedTiles [][]tiles.TileType, iterations int, initialRandomnessFactor float64,
-	rules []TerrainRule, rng *rand.Rand) ([][]Tile, error) {
+// GenerateTiles runs the rules on a random, painted or given grid. It stops
+// with ctx.Err() when ctx is canceled.
+func GenerateTiles(ctx context.Context, width, height int, paintedTiles [][]tiles.TileType, iterations int, initialRandomnessFactor float64,
+	rules []TerrainRule, rng *rand.Rand, opts Options) ([][]Tile, Stats, error) {
 
+	stats := Stats{StopReason: StopCompleted}
 	if len(paintedTiles) != height || len(paintedTiles[0]) != width {
-		return nil, errors.New("paintedTiles dimensions do not match provided dimensions")
+		return nil, stats, errors.New("paintedTiles dimensions do not match provided dimensions")
+	}
+	if opts.Initial != nil && !hasDims(opts.Initial, width, height) {
+		return nil, stats, errors.New("initial grid dimensions do not match provided dimensions")
+	}
+	if opts.Locked != nil && len(opts.Locked) != height {
+		return nil, stats, errors.New("lock mask dimensions do not match provided dimensions")
+	}
+	for _, row := range opts.Locked {
+		if len(row) != width {
+			return nil, stats, errors.New("lock mask dimensions do not match provided dimensions")
+		}
 	}
 
-	grid := initializeGrid(width, height, paintedTiles, rng)
+	if opts.Tiles == nil {
+		opts.Tiles = tiles.Default
+	}
+	grid := initializeGrid(width, height, paintedTiles, opts.Initial, opts.Tiles.Len(), rng)
+	nbh := opts.Neighborhood
+	if len(nbh) == 0 {
+		nbh = neighborhood.Default
+	}
+	if opts.Boundary == "" {
+		opts.Boundary = neighborhood.BoundaryFixed
+	}
+
+	if opts.SnapshotEvery > 0 {
+		stats.Frames = append(stats.Frames, cloneGrid(grid))
+	}
+
+	// Buffers are rotated instead of reallocated: prev holds the grid before
+	// the last iteration for 2-cycle detection, next receives the new state.
+	// prev is only kept when StopOnConvergence needs it.
+	var prev [][]Tile
+	next := newGrid(width, height)
+	scratch := make([]stepScratch, parallel.Bands(height, opts.Workers))
+	for i := range scratch {
+		scratch[i].rng = rand.New(&parallel.SplitMix{})
+	}
 
 	for i := 0; i < iterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, stats, err
+		}
 		// Increase the decay rate
 		decay := float64(i*i) / float64(iterations*iterations)
 		randomnessFactor := initialRandomnessFactor * (1.0 - decay)
 
-		grid = applyRules(grid, rules, width, height, randomnessFactor, rng)
+		// Each row draws from its own stream derived from this seed
+		changed := applyRules(grid, next, rules, width, height, randomnessFactor, rng.Int63(), nbh, opts, scratch)
+		stats.Changes = append(stats.Changes, changed)
+		stats.Iterations = i + 1
+		if opts.Progress != nil {
+			opts.Progress(stats.Iterations, iterations)
+		}
+
+		belowThreshold := float64(changed) < opts.MinChangeFraction*float64(width*height)
+		reason := StopCompleted
+		if opts.StopOnConvergence || belowThreshold {
+			switch {
+			case changed == 0:
+				reason = StopStable
+			case opts.StopOnConvergence && prev != nil && countChanges(prev, next) == 0:
+				reason = StopOscillating
+			case belowThreshold:
+				reason = StopThreshold
+			}
+		}
+		if opts.StopOnConvergence {
+			if prev == nil {
+				prev = newGrid(width, height)
+			}
+			prev, grid, next = grid, next, prev
+		} else {
+			grid, next = next, grid
+		}
+		if opts.Watch != nil {
+			opts.Watch(grid)
+		}
+		if opts.SnapshotEvery > 0 && stats.Iterations%opts.SnapshotEvery == 0 {
+			stats.Frames = append(stats.Frames, cloneGrid(grid))
+		}
+
+		if reason != StopCompleted {
+			stats.StopReason = reason
+			break
+		}
+	}
+	if opts.SnapshotEvery > 0 && stats.Iterations%opts.SnapshotEvery != 0 {
+		stats.Frames = append(stats.Frames, cloneGrid(grid))
+	}
+	log.Printf("MLCA stopped after %d of %d iterations (%s)", stats.Iterations, iterations, stats.StopReason)
 
-		log.Printf("Iteration %d complete", i)
+	return grid, stats, nil
+}
+
+func newGrid(width, height int) [][]Tile {
+	grid := make([][]Tile, height)
+	for y := range grid {
+		grid[y] = make([]Tile, width)
 	}
+	return grid
+}
 
-	return grid, nil
+func cloneGrid(grid [][]Tile) [][]Tile {
+	out := make([][]Tile, len(grid))
+	for y := range grid {
+		out[y] = append([]Tile(nil), grid[y]...)
+	}
+	return out
+}
+
+// countChanges returns the number of cells that differ between a and b.
+func countChanges(a, b [][]Tile) int {
+	changed := 0
+	for y := range a {
+		for x := range a[y] {
+			if a[y][x] != b[y][x] {
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+func hasDims(grid [][]tiles.TileType, width, height int) bool {
+	if len(grid) != height {
+		return false
+	}
+	for _, row := range grid {
+		if len(row) != width {
+			return false
+		}
+	}
+	return true
 }
 
-func initializeGrid(width, height int, paintedTiles [][]tiles.TileType, rng *rand.Rand) [][]Tile {
+// initializeGrid fills unpainted cells from initial, or randomly with one of
+// numTypes tile types when initial is nil
+func initializeGrid(width, height int, paintedTiles, initial [][]tiles.TileType, numTypes int, rng *rand.Rand) [][]Tile {
 	grid := make([][]Tile, height)
 	paintedTilesNum := 0
 	randomTilesNum := 0
@@ -131,58 +373,127 @@ func initializeGrid(width, height int, paintedTiles [][]tiles.TileType, rng *ran
 				grid[y][x] = Tile{Color: paintedTiles[y][x]}
 				paintedTilesNum += 1
 				//log.Printf("Initialized painted tile at (%d, %d) with color %d", x, y, paintedTiles[y][x])
+			} else if initial != nil {
+				grid[y][x] = Tile{Color: initial[y][x]}
 			} else {
-				randomColor := tiles.TileType(rng.Intn(8))
+				randomColor := tiles.TileType(rng.Intn(numTypes))
 				grid[y][x] = Tile{Color: randomColor}
 				randomTilesNum += 1
 				//log.Printf("Initialized random tile at (%d, %d) with color %d", x, y, randomColor)
 			}
 		}
 	}
-	log.Printf("Initialized %d painted tiles and %d random tiles", paintedTilesNum, randomTilesNum)
+	if initial != nil {
+		log.Printf("Initialized %d painted tiles on top of the previous grid", paintedTilesNum)
+	} else {
+		log.Printf("Initialized %d painted tiles and %d random tiles", paintedTilesNum, randomTilesNum)
+	}
 	return grid
 }
 
-func applyRules(grid [][]Tile, rules []TerrainRule, width, height int, randomnessFactor float64, rng *rand.Rand) [][]Tile {
-	nextGrid := make([][]Tile, height)
-	for y := 0; y < height; y++ {
-		nextGrid[y] = make([]Tile, width)
-		for x := 0; x < width; x++ {
-			currentTile := grid[y][x]
-			neighbors := getAdjacentTiles(grid, x, y, width, height)
-			ruleApplied := false
-			for _, rule := range rules {
-				if rule.Condition(currentTile, neighbors, x, y, grid, randomnessFactor, rng) {
-					nextGrid[y][x] = Tile{Color: rule.TargetColor}
-					//log.Printf("Applied rule from %d to %d at (%d, %d)", rule.SourceColor, rule.TargetColor, x, y)
-					ruleApplied = true
-					break
+// stepScratch is per-band state reused across iterations.
+type stepScratch struct {
+	neighbors []Tile
+	matches   []int
+	rng       *rand.Rand
+}
+
+// applyRules computes one iteration from grid into next, splitting rows across
+// workers, and returns the number of changed cells.
+func applyRules(grid, next [][]Tile, rules []TerrainRule, width, height int, randomnessFactor float64, seed int64,
+	nbh neighborhood.Neighborhood, opts Options, scratch []stepScratch) int {
+	changes := make([]int, len(scratch))
+	parallel.Rows(height, opts.Workers, func(band, y0, y1 int) {
+		s := &scratch[band]
+		changed := 0
+		for y := y0; y < y1; y++ {
+			s.rng.Seed(parallel.RowSeed(seed, y))
+			for x := 0; x < width; x++ {
+				currentTile := grid[y][x]
+				nextTile := currentTile
+				if opts.Locked == nil || !opts.Locked[y][x] {
+					s.neighbors = gatherNeighbors(s.neighbors[:0], grid, x, y, width, height, nbh, opts)
+					s.matches = matchRules(s.matches[:0], rules, currentTile, s.neighbors, x, y, grid, randomnessFactor, s.rng, opts.Selection)
+					if len(s.matches) > 0 {
+						nextTile = Tile{Color: rules[selectRule(s.matches, rules, s.rng, opts.Selection)].TargetColor}
+					}
+				}
+				next[y][x] = nextTile
+				if nextTile != currentTile {
+					changed++
 				}
 			}
-			if !ruleApplied {
-				nextGrid[y][x] = currentTile
+		}
+		changes[band] = changed
+	})
+	total := 0
+	for _, c := range changes {
+		total += c
+	}
+	return total
+}
+
+// matchRules appends the indices of all rules that fire for the tile. Under
+// SelectFirst it stops at the first match.
+func matchRules(matches []int, rules []TerrainRule, t Tile, neighbors []Tile, x, y int, grid [][]Tile,
+	randomnessFactor float64, rng *rand.Rand, selection RuleSelection) []int {
+	for i, rule := range rules {
+		if rule.Condition(t, neighbors, x, y, grid, randomnessFactor, rng) {
+			matches = append(matches, i)
+			if selection == SelectFirst || selection == "" {
+				break
 			}
 		}
 	}
-	return nextGrid
+	return matches
 }
 
-// getAdjacentTiles returns 8 neighbors including out-of-bounds as DeepWater
-func getAdjacentTiles(grid [][]Tile, x, y, width, height int) []Tile {
-	coordinates := []struct{ dx, dy int }{
-		{-1, -1}, {-1, 0}, {-1, 1},
-		{0, -1}, {0, 1},
-		{1, -1}, {1, 0}, {1, 1},
+// selectRule resolves a non-empty set of matching rules to a single rule index.
+func selectRule(matches []int, rules []TerrainRule, rng *rand.Rand, selection RuleSelection) int {
+	switch selection {
+	case SelectWeighted:
+		// Each rule passed its probability in Condition, so the pick is
+		// uniform; weighing again would apply the probability twice
+		return matches[rng.Intn(len(matches))]
+	case SelectPriority:
+		best := matches[0]
+		for _, i := range matches[1:] {
+			if rules[i].Priority > rules[best].Priority {
+				best = i
+			}
+		}
+		return best
 	}
-	neighbors := make([]Tile, 0, 8)
-	for _, c := range coordinates {
-		nx, ny := x+c.dx, y+c.dy
-		if nx >= 0 && nx < width && ny >= 0 && ny < height {
-			neighbors = append(neighbors, grid[ny][nx])
+	return matches[0]
+}
+
+// gatherNeighbors appends the neighbors in nbh to buf, resolving out-of-bounds
+// cells according to the boundary options
+func gatherNeighbors(buf []Tile, grid [][]Tile, x, y, width, height int, nbh neighborhood.Neighborhood, opts Options) []Tile {
+	for _, o := range nbh {
+		nx, ny, ok := opts.Boundary.Resolve(x+o.DX, y+o.DY, width, height)
+		var t Tile
+		if ok {
+			t = grid[ny][nx]
+		} else if opts.Boundary == neighborhood.BoundaryFixed {
+			t = Tile{Color: opts.BoundaryTile}
 		} else {
-			// außerhalb der Karte → als Wasser behandeln:
-			neighbors = append(neighbors, Tile{Color: tiles.DeepWater})
+			continue
+		}
+		for n := o.Times(); n > 0; n-- {
+			buf = append(buf, t)
+		}
+	}
+	return buf
+}
+
+func TilesToIntGrid(grid [][]Tile) [][]int {
+	res := make([][]int, len(grid))
+	for y := range grid {
+		res[y] = make([]int, len(grid[y]))
+		for x := range grid[y] {
+			res[y][x] = int(grid[y][x].Color)
 		}
 	}
-	return neighbors
+	return res
 }
@@ -0,0 +1,262 @@
+package mlca
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+func TestMain(m *testing.M) {
+	// GenerateTiles logs every run
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func unpainted(width, height int) [][]tiles.TileType {
+	painted := make([][]tiles.TileType, height)
+	for y := range painted {
+		painted[y] = make([]tiles.TileType, width)
+		for x := range painted[y] {
+			painted[y][x] = -1
+		}
+	}
+	return painted
+}
+
+func run(t testing.TB, size, iterations int, randomness float64, opts Options) [][]Tile {
+	t.Helper()
+	grid, _, err := GenerateTiles(context.Background(), size, size, unpainted(size, size), iterations, randomness,
+		CreateDefaultRules(), rand.New(rand.NewSource(1)), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return grid
+}
+
+func TestDeterministicAcrossWorkers(t *testing.T) {
+	for _, selection := range []RuleSelection{SelectFirst, SelectWeighted, SelectPriority} {
+		want := run(t, 64, 20, 0.5, Options{Selection: selection, Workers: 1})
+		for _, workers := range []int{2, 3, runtime.NumCPU()} {
+			got := run(t, 64, 20, 0.5, Options{Selection: selection, Workers: workers})
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s selection with %d workers differs from 1 worker", selection, workers)
+			}
+		}
+	}
+}
+
+func BenchmarkApplyRules(b *testing.B) {
+	counts := []int{1, 2, 4, runtime.NumCPU()}
+	slices.Sort(counts)
+	for _, workers := range slices.Compact(counts) {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				run(b, 512, 50, 0.5, Options{Workers: workers})
+			}
+		})
+	}
+}
+
+// uniform returns a width x height grid of tile t.
+func uniform(width, height int, t tiles.TileType) [][]tiles.TileType {
+	grid := make([][]tiles.TileType, height)
+	for y := range grid {
+		grid[y] = make([]tiles.TileType, width)
+		for x := range grid[y] {
+			grid[y][x] = t
+		}
+	}
+	return grid
+}
+
+// shares returns the share of each tile type in grid.
+func shares(grid [][]Tile) map[tiles.TileType]float64 {
+	out := map[tiles.TileType]float64{}
+	for _, row := range grid {
+		for _, c := range row {
+			out[c.Color] += 1 / float64(len(grid)*len(row))
+		}
+	}
+	return out
+}
+
+// always returns a rule from source to target that matches every cell.
+func always(source, target tiles.TileType, probability *float64, priority int) TerrainRule {
+	return TerrainRule{SourceColor: source, TargetColor: target, MinCount: -1, MaxCount: -1, Probability: probability, Priority: priority}
+}
+
+func TestRuleProbabilities(t *testing.T) {
+	p := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name      string
+		rules     []TerrainRule
+		selection RuleSelection
+		// want is the expected share of each tile after one iteration
+		want map[tiles.TileType]float64
+	}{
+		{"always", []TerrainRule{always(tiles.Sand, tiles.Grass, nil, 0)}, SelectFirst,
+			map[tiles.TileType]float64{tiles.Grass: 1}},
+		{"never", []TerrainRule{always(tiles.Sand, tiles.Grass, p(0), 0)}, SelectFirst,
+			map[tiles.TileType]float64{tiles.Sand: 1}},
+		{"quarter", []TerrainRule{always(tiles.Sand, tiles.Grass, p(0.25), 0)}, SelectFirst,
+			map[tiles.TileType]float64{tiles.Sand: 0.75, tiles.Grass: 0.25}},
+		// The forest rule fires half the time and then wins half of the picks
+		{"weighted", []TerrainRule{always(tiles.Sand, tiles.Grass, nil, 0), always(tiles.Sand, tiles.Forest, p(0.5), 0)}, SelectWeighted,
+			map[tiles.TileType]float64{tiles.Grass: 0.75, tiles.Forest: 0.25}},
+		{"priority", []TerrainRule{always(tiles.Sand, tiles.Grass, nil, 1), always(tiles.Sand, tiles.Forest, p(0.5), 2)}, SelectPriority,
+			map[tiles.TileType]float64{tiles.Grass: 0.5, tiles.Forest: 0.5}},
+		{"first", []TerrainRule{always(tiles.Sand, tiles.Grass, p(0.5), 0), always(tiles.Sand, tiles.Forest, nil, 0)}, SelectFirst,
+			map[tiles.TileType]float64{tiles.Grass: 0.5, tiles.Forest: 0.5}},
+	}
+	const size = 100
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid, _, err := GenerateTiles(context.Background(), size, size, unpainted(size, size), 1, 0,
+				tt.rules, rand.New(rand.NewSource(1)), Options{Selection: tt.selection, Initial: uniform(size, size, tiles.Sand)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := shares(grid)
+			for tile, share := range tt.want {
+				if math.Abs(got[tile]-share) > 0.03 {
+					t.Errorf("tile %d has share %.3f, want %.3f", tile, got[tile], share)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRuleSelection(t *testing.T) {
+	for in, want := range map[string]RuleSelection{"": SelectFirst, "first": SelectFirst, "weighted": SelectWeighted, "priority": SelectPriority} {
+		if got, err := ParseRuleSelection(in); err != nil || got != want {
+			t.Errorf("ParseRuleSelection(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseRuleSelection("random"); err == nil {
+		t.Error(`ParseRuleSelection("random") succeeded`)
+	}
+}
+
+func TestConvergence(t *testing.T) {
+	p := func(v float64) *float64 { return &v }
+	// Sand and grass swap every iteration
+	swap := []TerrainRule{always(tiles.Sand, tiles.Grass, nil, 0), always(tiles.Grass, tiles.Sand, nil, 0)}
+	tests := []struct {
+		name       string
+		rules      []TerrainRule
+		opts       Options
+		iterations int
+		reason     StopReason
+	}{
+		{"stable", nil, Options{StopOnConvergence: true}, 1, StopStable},
+		{"stable without stopping", nil, Options{}, 10, StopCompleted},
+		{"oscillating", swap, Options{StopOnConvergence: true}, 2, StopOscillating},
+		{"oscillating without stopping", swap, Options{}, 10, StopCompleted},
+		{"threshold", []TerrainRule{always(tiles.Sand, tiles.Grass, p(0.01), 0)}, Options{MinChangeFraction: 0.05}, 1, StopThreshold},
+		{"above threshold", swap, Options{MinChangeFraction: 0.05}, 10, StopCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Initial = uniform(20, 20, tiles.Sand)
+			_, stats, err := GenerateTiles(context.Background(), 20, 20, unpainted(20, 20), 10, 0,
+				tt.rules, rand.New(rand.NewSource(1)), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Iterations != tt.iterations || stats.StopReason != tt.reason || len(stats.Changes) != tt.iterations {
+				t.Errorf("stopped after %d iterations (%s), want %d (%s)", stats.Iterations, stats.StopReason, tt.iterations, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	swap := []TerrainRule{always(tiles.Sand, tiles.Grass, nil, 0), always(tiles.Grass, tiles.Sand, nil, 0)}
+	tests := []struct {
+		iterations, every, frames int
+	}{
+		{10, 0, 0},
+		// The initial grid, iterations 3, 6 and 9, and the final grid
+		{10, 3, 5},
+		{10, 5, 3},
+		{10, 10, 2},
+		{4, 10, 2},
+	}
+	for _, tt := range tests {
+		grid, stats, err := GenerateTiles(context.Background(), 4, 4, unpainted(4, 4), tt.iterations, 0,
+			swap, rand.New(rand.NewSource(1)), Options{SnapshotEvery: tt.every, Initial: uniform(4, 4, tiles.Sand)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats.Frames) != tt.frames {
+			t.Errorf("%d iterations every %d: %d frames, want %d", tt.iterations, tt.every, len(stats.Frames), tt.frames)
+			continue
+		}
+		if tt.frames == 0 {
+			continue
+		}
+		if stats.Frames[0][0][0].Color != tiles.Sand || !reflect.DeepEqual(stats.Frames[tt.frames-1], grid) {
+			t.Errorf("%d iterations every %d: frames do not start with the initial and end with the final grid", tt.iterations, tt.every)
+		}
+	}
+}
+
+func TestInitialGrid(t *testing.T) {
+	initial := [][]tiles.TileType{{0, 1, 2}, {3, 4, 5}}
+	painted := [][]tiles.TileType{{-1, 7, -1}, {-1, -1, -1}}
+	grid, _, err := GenerateTiles(context.Background(), 3, 2, painted, 0, 0, nil,
+		rand.New(rand.NewSource(1)), Options{Initial: initial})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Painted tiles override the grid continued from
+	want := [][]int{{0, 7, 2}, {3, 4, 5}}
+	if got := TilesToIntGrid(grid); !reflect.DeepEqual(got, want) {
+		t.Errorf("grid = %v, want %v", got, want)
+	}
+
+	for _, initial := range [][][]tiles.TileType{{{0, 1, 2}}, {{0, 1}, {2, 3}}} {
+		if _, _, err := GenerateTiles(context.Background(), 3, 2, painted, 1, 0, nil,
+			rand.New(rand.NewSource(1)), Options{Initial: initial}); err == nil {
+			t.Errorf("initial grid %v of the wrong size accepted", initial)
+		}
+	}
+}
+
+func TestLockedCells(t *testing.T) {
+	swap := []TerrainRule{always(tiles.Sand, tiles.Grass, nil, 0), always(tiles.Grass, tiles.Sand, nil, 0)}
+	locked := [][]bool{{true, false, false}, {false, false, true}}
+	for _, workers := range []int{1, 2} {
+		grid, stats, err := GenerateTiles(context.Background(), 3, 2, unpainted(3, 2), 3, 0, swap,
+			rand.New(rand.NewSource(1)), Options{Initial: uniform(3, 2, tiles.Sand), Locked: locked, Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Unlocked cells swap three times and end as grass (5), locked ones
+		// keep their sand (4)
+		want := [][]int{{4, 5, 5}, {5, 5, 4}}
+		if got := TilesToIntGrid(grid); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers: grid = %v, want %v", workers, got, want)
+		}
+		if stats.Changes[0] != 4 {
+			t.Errorf("%d workers: %d cells changed, want 4", workers, stats.Changes[0])
+		}
+	}
+
+	for _, locked := range [][][]bool{{{true}}, {{true, false, false}}, {{true, false}, {false, true}}} {
+		if _, _, err := GenerateTiles(context.Background(), 3, 2, unpainted(3, 2), 1, 0, swap,
+			rand.New(rand.NewSource(1)), Options{Locked: locked}); err == nil {
+			t.Errorf("lock mask %v of the wrong size accepted", locked)
+		}
+	}
+}
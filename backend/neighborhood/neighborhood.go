@@ -0,0 +1,187 @@
+package neighborhood
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Offset is a relative neighbor position. Weight says how often the neighbor
+// is counted; zero is treated as 1.
+type Offset struct {
+	DX     int `json:"dx"`
+	DY     int `json:"dy"`
+	Weight int `json:"weight,omitempty"`
+}
+
+// Neighborhood is the list of offsets a cellular automaton looks at.
+type Neighborhood []Offset
+
+const (
+	KindMoore      = "moore"
+	KindVonNeumann = "vonneumann"
+	KindCustom     = "custom"
+)
+
+// Default is the 8-cell Moore neighborhood used before neighborhoods were configurable.
+var Default = Moore(1)
+
+// Moore returns all cells within Chebyshev distance r, excluding the center.
+func Moore(r int) Neighborhood {
+	var n Neighborhood
+	for dx := -r; dx <= r; dx++ {
+		for dy := -r; dy <= r; dy++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			n = append(n, Offset{DX: dx, DY: dy, Weight: 1})
+		}
+	}
+	return n
+}
+
+// VonNeumann returns all cells within Manhattan distance r, excluding the center.
+func VonNeumann(r int) Neighborhood {
+	var n Neighborhood
+	for dx := -r; dx <= r; dx++ {
+		for dy := -r; dy <= r; dy++ {
+			if (dx == 0 && dy == 0) || abs(dx)+abs(dy) > r {
+				continue
+			}
+			n = append(n, Offset{DX: dx, DY: dy, Weight: 1})
+		}
+	}
+	return n
+}
+
+// FromSpec builds a neighborhood from request parameters. An empty kind gives
+// Default; a radius <= 0 is treated as 1.
+func FromSpec(kind string, radius int, kernel []Offset) (Neighborhood, error) {
+	if radius <= 0 {
+		radius = 1
+	}
+	switch kind {
+	case "":
+		if len(kernel) > 0 {
+			return fromKernel(kernel)
+		}
+		return Default, nil
+	case KindMoore:
+		return Moore(radius), nil
+	case KindVonNeumann:
+		return VonNeumann(radius), nil
+	case KindCustom:
+		return fromKernel(kernel)
+	}
+	return nil, fmt.Errorf("unknown neighborhood %q", kind)
+}
+
+func fromKernel(kernel []Offset) (Neighborhood, error) {
+	if len(kernel) == 0 {
+		return nil, errors.New("custom neighborhood needs at least one offset")
+	}
+	n := make(Neighborhood, 0, len(kernel))
+	for _, o := range kernel {
+		if o.Weight < 0 {
+			return nil, fmt.Errorf("negative weight at offset (%d,%d)", o.DX, o.DY)
+		}
+		if o.Weight == 0 {
+			o.Weight = 1
+		}
+		n = append(n, o)
+	}
+	return n, nil
+}
+
+// Size is the total weight of the neighborhood, i.e. the largest possible count.
+func (n Neighborhood) Size() int {
+	total := 0
+	for _, o := range n {
+		total += o.Times()
+	}
+	return total
+}
+
+// Times is how often the offset is counted, i.e. its weight with zero treated as 1.
+func (o Offset) Times() int {
+	if o.Weight <= 0 {
+		return 1
+	}
+	return o.Weight
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Boundary says how neighbors outside the grid are resolved.
+type Boundary string
+
+const (
+	BoundaryFixed  Boundary = "fixed"  // use a fixed value supplied by the caller
+	BoundaryClamp  Boundary = "clamp"  // use the nearest edge cell
+	BoundaryMirror Boundary = "mirror" // reflect across the edge
+	BoundaryWrap   Boundary = "wrap"   // toroidal grid
+	BoundaryIgnore Boundary = "ignore" // drop the neighbor
+)
+
+// ParseBoundary maps a request value to a Boundary. Empty gives def.
+func ParseBoundary(s string, def Boundary) (Boundary, error) {
+	switch b := Boundary(s); b {
+	case "":
+		return def, nil
+	case BoundaryFixed, BoundaryClamp, BoundaryMirror, BoundaryWrap, BoundaryIgnore:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown boundary %q", s)
+}
+
+// Resolve maps (x, y) into the width x height grid. ok is false when the
+// position lies outside and the boundary is BoundaryFixed or BoundaryIgnore.
+func (b Boundary) Resolve(x, y, width, height int) (int, int, bool) {
+	if x >= 0 && x < width && y >= 0 && y < height {
+		return x, y, true
+	}
+	switch b {
+	case BoundaryClamp:
+		return clamp(x, width), clamp(y, height), true
+	case BoundaryMirror:
+		return mirror(x, width), mirror(y, height), true
+	case BoundaryWrap:
+		return wrap(x, width), wrap(y, height), true
+	}
+	return x, y, false
+}
+
+func clamp(v, n int) int {
+	if v < 0 {
+		return 0
+	}
+	if v >= n {
+		return n - 1
+	}
+	return v
+}
+
+// mirror reflects v without repeating the edge cell: -1 → 1, n → n-2.
+func mirror(v, n int) int {
+	if n == 1 {
+		return 0
+	}
+	period := 2 * (n - 1)
+	v = wrap(v, period)
+	if v >= n {
+		v = period - v
+	}
+	return v
+}
+
+func wrap(v, n int) int {
+	v %= n
+	if v < 0 {
+		v += n
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+package neighborhood
+
+import "testing"
+
+func TestFromSpec(t *testing.T) {
+	tests := []struct {
+		kind   string
+		radius int
+		kernel []Offset
+		// size is the total weight, -1 expects an error
+		size int
+	}{
+		{"", 0, nil, 8},
+		{"", 3, nil, 8},
+		{KindMoore, 0, nil, 8},
+		{KindMoore, 2, nil, 24},
+		{KindMoore, 3, nil, 48},
+		{KindVonNeumann, 1, nil, 4},
+		{KindVonNeumann, 2, nil, 12},
+		{KindCustom, 1, []Offset{{DX: 1}, {DX: -1, Weight: 3}}, 4},
+		// A kernel without a kind is custom
+		{"", 1, []Offset{{DY: 2, Weight: 2}}, 2},
+		{KindCustom, 1, nil, -1},
+		{KindCustom, 1, []Offset{{DX: 1, Weight: -1}}, -1},
+		{"hex", 1, nil, -1},
+	}
+	for _, tt := range tests {
+		n, err := FromSpec(tt.kind, tt.radius, tt.kernel)
+		if tt.size < 0 {
+			if err == nil {
+				t.Errorf("FromSpec(%q, %d, %v) succeeded, want an error", tt.kind, tt.radius, tt.kernel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FromSpec(%q, %d, %v): %v", tt.kind, tt.radius, tt.kernel, err)
+			continue
+		}
+		if n.Size() != tt.size {
+			t.Errorf("FromSpec(%q, %d, %v) has size %d, want %d", tt.kind, tt.radius, tt.kernel, n.Size(), tt.size)
+		}
+		for _, o := range n {
+			if o.DX == 0 && o.DY == 0 && tt.kernel == nil {
+				t.Errorf("FromSpec(%q, %d) includes the center", tt.kind, tt.radius)
+			}
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	const width, height = 4, 3
+	tests := []struct {
+		boundary Boundary
+		x, y     int
+		wantX    int
+		wantY    int
+		ok       bool
+	}{
+		{BoundaryFixed, 2, 1, 2, 1, true},
+		{BoundaryFixed, -1, 0, -1, 0, false},
+		{BoundaryIgnore, 0, 3, 0, 3, false},
+		{BoundaryClamp, -2, 5, 0, 2, true},
+		{BoundaryClamp, 4, -1, 3, 0, true},
+		{BoundaryWrap, -1, 3, 3, 0, true},
+		{BoundaryWrap, 9, -4, 1, 2, true},
+		// Mirror does not repeat the edge cell
+		{BoundaryMirror, -1, 3, 1, 1, true},
+		{BoundaryMirror, 4, -2, 2, 2, true},
+		{BoundaryMirror, 7, 0, 1, 0, true},
+	}
+	for _, tt := range tests {
+		x, y, ok := tt.boundary.Resolve(tt.x, tt.y, width, height)
+		if ok != tt.ok || (ok && (x != tt.wantX || y != tt.wantY)) {
+			t.Errorf("%s.Resolve(%d, %d) = %d, %d, %t, want %d, %d, %t",
+				tt.boundary, tt.x, tt.y, x, y, ok, tt.wantX, tt.wantY, tt.ok)
+		}
+	}
+	if x, _, _ := BoundaryMirror.Resolve(-3, 0, 1, 1); x != 0 {
+		t.Errorf("mirror on a 1-cell grid gave %d, want 0", x)
+	}
+}
+
+func TestParseBoundary(t *testing.T) {
+	if b, err := ParseBoundary("", BoundaryWrap); err != nil || b != BoundaryWrap {
+		t.Errorf(`ParseBoundary("") = %q, %v, want the default`, b, err)
+	}
+	for _, b := range []Boundary{BoundaryFixed, BoundaryClamp, BoundaryMirror, BoundaryWrap, BoundaryIgnore} {
+		if got, err := ParseBoundary(string(b), BoundaryFixed); err != nil || got != b {
+			t.Errorf("ParseBoundary(%q) = %q, %v", b, got, err)
+		}
+	}
+	if _, err := ParseBoundary("torus", BoundaryFixed); err == nil {
+		t.Error(`ParseBoundary("torus") succeeded`)
+	}
+}
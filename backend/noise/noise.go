@@ -5,6 +5,7 @@ import (
 	"log"
 	"procedural-map-generation-toolkit/backend/mlca"
 	"procedural-map-generation-toolkit/backend/tiles"
+	"sort"
 )
 
 // Generator generates a map based on Perlin-Noise
@@ -14,10 +15,12 @@ type Generator struct {
 	Octaves     int     // Number of Octaves
 	Persistence float64 // Amplitude-degen per octave
 	Lacunarity  float64 // Frequency-Multiplication per octave
-	thresholds  []struct {
-		Max   float64        // Upper limit of normalized noise-value
-		Color tiles.TileType // Assign to TileColorType
-	}
+	thresholds  []threshold
+}
+
+type threshold struct {
+	Max   float64        // Upper limit of normalized noise-value
+	Color tiles.TileType // Assign to TileColorType
 }
 
 // NewNoiseGenerator creates a new NoiseGenerator with given parameters:
@@ -30,29 +33,32 @@ func NewNoiseGenerator(seed int64, scale float64, octaves int, persistence, lacu
 	// alpha = persistence, beta = lacunarity, n = octaves
 	p := perlin.NewPerlin(persistence, lacunarity, int32(octaves), seed)
 
-	// Define Thresholds for conversion of noise-values to tile-colors
-	thresholds := []struct {
-		Max   float64
-		Color tiles.TileType
-	}{
-		{0.2, tiles.DeepWater},
-		{0.4, tiles.Water},
-		{0.5, tiles.CoastalWater},
-		{0.55, tiles.WetSand},
-		{0.6, tiles.Sand},
-		{0.7, tiles.Grass},
-		{0.8, tiles.Bushes},
-		{1.0, tiles.Forest},
-	}
-
-	return &Generator{
+	ng := &Generator{
 		perlin:      p,
 		Scale:       scale,
 		Octaves:     octaves,
 		Persistence: persistence,
 		Lacunarity:  lacunarity,
-		thresholds:  thresholds,
 	}
+	ng.UseTiles(tiles.Default)
+	return ng
+}
+
+// UseTiles maps noise values to the tiles of set by their elevation, or to
+// equally wide bands in id order when no tile has an elevation.
+func (ng *Generator) UseTiles(set *tiles.Set) {
+	ng.thresholds = ng.thresholds[:0]
+	for _, t := range set.Tiles {
+		if t.Elevation > 0 {
+			ng.thresholds = append(ng.thresholds, threshold{t.Elevation, t.ID})
+		}
+	}
+	if len(ng.thresholds) == 0 {
+		for i, t := range set.Tiles {
+			ng.thresholds = append(ng.thresholds, threshold{float64(i+1) / float64(set.Len()), t.ID})
+		}
+	}
+	sort.SliceStable(ng.thresholds, func(i, j int) bool { return ng.thresholds[i].Max < ng.thresholds[j].Max })
 }
 
 // Generate a grid with width x height.
@@ -107,5 +113,5 @@ func (ng *Generator) mapValueToColor(val float64) tiles.TileType {
 			return t.Color
 		}
 	}
-	return tiles.Forest
+	return ng.thresholds[len(ng.thresholds)-1].Color
 }
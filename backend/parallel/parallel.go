@@ -0,0 +1,80 @@
+package parallel
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Workers returns n, or the number of usable CPUs when n <= 0.
+func Workers(n int) int {
+	if n <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return n
+}
+
+// Rows splits [0, height) into contiguous bands and calls fn for each band on
+// its own goroutine. It returns once all bands are done. The band index is
+// passed so callers can keep per-worker state.
+func Rows(height, workers int, fn func(band, y0, y1 int)) {
+	workers = Workers(workers)
+	if workers > height {
+		workers = height
+	}
+	if workers <= 1 {
+		if height > 0 {
+			fn(0, 0, height)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	size := (height + workers - 1) / workers
+	band := 0
+	for y0 := 0; y0 < height; y0 += size {
+		y1 := min(y0+size, height)
+		wg.Add(1)
+		go func(band, y0, y1 int) {
+			defer wg.Done()
+			fn(band, y0, y1)
+		}(band, y0, y1)
+		band++
+	}
+	wg.Wait()
+}
+
+// Bands returns the number of bands Rows will use, for sizing per-band state.
+func Bands(height, workers int) int {
+	workers = Workers(workers)
+	if workers > height {
+		workers = height
+	}
+	if workers <= 1 {
+		return 1
+	}
+	size := (height + workers - 1) / workers
+	return (height + size - 1) / size
+}
+
+// SplitMix is a small, cheaply reseeded rand.Source64 used for per-row random
+// streams, so results do not depend on how rows are split across workers.
+type SplitMix struct {
+	state uint64
+}
+
+func (s *SplitMix) Seed(seed int64) { s.state = uint64(seed) }
+
+func (s *SplitMix) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *SplitMix) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+// RowSeed derives the seed of a row's random stream from an iteration seed.
+func RowSeed(seed int64, row int) int64 {
+	s := SplitMix{state: uint64(seed) ^ uint64(row)*0xd1b54a32d192ed03}
+	return s.Int63()
+}
@@ -0,0 +1,63 @@
+package parallel
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	tests := []struct {
+		height, workers int
+	}{
+		{0, 4}, {1, 4}, {7, 1}, {7, 3}, {10, 4}, {16, 4}, {3, 8}, {100, 7},
+	}
+	for _, tt := range tests {
+		var mu sync.Mutex
+		seen := make([]int, tt.height)
+		bands := map[int]bool{}
+		Rows(tt.height, tt.workers, func(band, y0, y1 int) {
+			mu.Lock()
+			defer mu.Unlock()
+			bands[band] = true
+			for y := y0; y < y1; y++ {
+				seen[y]++
+			}
+		})
+		for y, n := range seen {
+			if n != 1 {
+				t.Errorf("Rows(%d, %d) visited row %d %d times", tt.height, tt.workers, y, n)
+			}
+		}
+		// Bands sizes per-band state, so every band index must be below it
+		n := Bands(tt.height, tt.workers)
+		for band := range bands {
+			if band < 0 || band >= n {
+				t.Errorf("Rows(%d, %d) used band %d, Bands = %d", tt.height, tt.workers, band, n)
+			}
+		}
+		if tt.height > 0 && len(bands) != n {
+			t.Errorf("Rows(%d, %d) used %d bands, Bands = %d", tt.height, tt.workers, len(bands), n)
+		}
+	}
+}
+
+func TestRowSeed(t *testing.T) {
+	if RowSeed(1, 2) != RowSeed(1, 2) {
+		t.Error("RowSeed is not deterministic")
+	}
+	seeds := map[int64]bool{}
+	for row := 0; row < 100; row++ {
+		seeds[RowSeed(5, row)] = true
+	}
+	if len(seeds) != 100 {
+		t.Errorf("100 rows got %d distinct seeds", len(seeds))
+	}
+	var a, b SplitMix
+	a.Seed(9)
+	b.Seed(9)
+	for i := 0; i < 10; i++ {
+		if x, y := a.Int63(), b.Int63(); x != y || x < 0 {
+			t.Fatalf("draw %d: %d and %d from the same seed", i, x, y)
+		}
+	}
+}
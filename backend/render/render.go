@@ -0,0 +1,211 @@
+// Package render draws tile grids as PNG or SVG images.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Formats lists the supported image formats.
+var Formats = []string{FormatPNG, FormatSVG}
+
+const (
+	// DefaultCellSize is the number of pixels per tile when Options.CellSize is zero.
+	DefaultCellSize = 8
+	// MaxCellSize bounds Options.CellSize.
+	MaxCellSize = 64
+)
+
+// Options configures rendering; the zero value draws 8 pixels per tile
+// without grid lines.
+type Options struct {
+	// CellSize is the number of pixels per tile.
+	CellSize int
+	// GridLines draws a line along the top and left edge of every tile.
+	GridLines bool
+	// GridColor is the "#rrggbb" color of grid lines; empty means black.
+	GridColor string
+	// Unknown is the color of values outside the palette, e.g. -1 for
+	// unpainted cells; nil means transparent.
+	Unknown color.Color
+}
+
+func (o Options) cellSize() int {
+	if o.CellSize <= 0 {
+		return DefaultCellSize
+	}
+	return o.CellSize
+}
+
+// ContentType returns the MIME type of format.
+func ContentType(format string) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Render encodes grid in the given format, "png" or "svg".
+func Render(format string, grid [][]int, colors []string, opts Options) ([]byte, error) {
+	switch format {
+	case FormatPNG:
+		return PNG(grid, colors, opts)
+	case FormatSVG:
+		return SVG(grid, colors, opts)
+	}
+	return nil, fmt.Errorf("unknown image format %q", format)
+}
+
+// Image draws grid with one palette entry per tile color, followed by the
+// unknown and the grid line colors.
+func Image(grid [][]int, colors []string, opts Options) (*image.Paletted, error) {
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return nil, fmt.Errorf("empty grid")
+	}
+	if len(colors) > 254 {
+		return nil, fmt.Errorf("too many colors: %d", len(colors))
+	}
+	palette, err := Palette(colors)
+	if err != nil {
+		return nil, err
+	}
+	unknown := uint8(len(palette))
+	if opts.Unknown != nil {
+		palette = append(palette, opts.Unknown)
+	} else {
+		palette = append(palette, color.Transparent)
+	}
+	lines := uint8(len(palette))
+	lineColor := color.RGBA{A: 0xff}
+	if opts.GridColor != "" {
+		if lineColor, err = ParseHexColor(opts.GridColor); err != nil {
+			return nil, err
+		}
+	}
+	palette = append(palette, lineColor)
+
+	cell := opts.cellSize()
+	height, width := len(grid), len(grid[0])
+	img := image.NewPaletted(image.Rect(0, 0, width*cell, height*cell), palette)
+	for y := range grid {
+		for x, v := range grid[y] {
+			if x >= width {
+				break
+			}
+			idx := unknown
+			if v >= 0 && v < int(unknown) {
+				idx = uint8(v)
+			}
+			for py := y * cell; py < (y+1)*cell; py++ {
+				row := img.Pix[py*img.Stride:]
+				for px := x * cell; px < (x+1)*cell; px++ {
+					row[px] = idx
+					if opts.GridLines && (px == x*cell || py == y*cell) {
+						row[px] = lines
+					}
+				}
+			}
+		}
+	}
+	return img, nil
+}
+
+// PNG renders grid as a PNG image.
+func PNG(grid [][]int, colors []string, opts Options) ([]byte, error) {
+	img, err := Image(grid, colors, opts)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders grid as an SVG image with one rectangle per horizontal run of
+// equal tiles. Values outside the palette are left empty.
+func SVG(grid [][]int, colors []string, opts Options) ([]byte, error) {
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return nil, fmt.Errorf("empty grid")
+	}
+	for _, c := range colors {
+		if _, err := ParseHexColor(c); err != nil {
+			return nil, err
+		}
+	}
+	lineColor := "#000000"
+	if opts.GridColor != "" {
+		if _, err := ParseHexColor(opts.GridColor); err != nil {
+			return nil, err
+		}
+		lineColor = opts.GridColor
+	}
+
+	cell := opts.cellSize()
+	height, width := len(grid), len(grid[0])
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		width*cell, height*cell, width*cell, height*cell)
+	for y, row := range grid {
+		for x := 0; x < len(row) && x < width; {
+			v := row[x]
+			run := 1
+			for x+run < len(row) && x+run < width && row[x+run] == v {
+				run++
+			}
+			if v >= 0 && v < len(colors) {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+					x*cell, y*cell, run*cell, cell, colors[v])
+			}
+			x += run
+		}
+	}
+	if opts.GridLines {
+		fmt.Fprintf(&b, `<g stroke="%s" stroke-width="1">`+"\n", lineColor)
+		for y := 0; y < height; y++ {
+			fmt.Fprintf(&b, `<line x1="0" y1="%g" x2="%d" y2="%g"/>`+"\n", float64(y*cell)+0.5, width*cell, float64(y*cell)+0.5)
+		}
+		for x := 0; x < width; x++ {
+			fmt.Fprintf(&b, `<line x1="%g" y1="0" x2="%g" y2="%d"/>`+"\n", float64(x*cell)+0.5, float64(x*cell)+0.5, height*cell)
+		}
+		b.WriteString("</g>\n")
+	}
+	b.WriteString("</svg>\n")
+	return []byte(b.String()), nil
+}
+
+// Palette parses "#rrggbb" colors.
+func Palette(colors []string) (color.Palette, error) {
+	palette := make(color.Palette, 0, len(colors)+2)
+	for _, hex := range colors {
+		c, err := ParseHexColor(hex)
+		if err != nil {
+			return nil, err
+		}
+		palette = append(palette, c)
+	}
+	return palette, nil
+}
+
+// ParseHexColor parses "#rrggbb".
+func ParseHexColor(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
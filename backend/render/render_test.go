@@ -0,0 +1,121 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+var testColors = []string{"#000000", "#ff0000", "#00ff00", "#0000ff"}
+
+func TestPNG(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	tests := []struct {
+		name string
+		grid [][]int
+		opts Options
+		// pixels maps points to their expected color
+		pixels map[[2]int]color.Color
+		size   [2]int
+	}{
+		{"default cell size", [][]int{{1, 3}}, Options{},
+			map[[2]int]color.Color{{0, 0}: red, {7, 7}: red, {8, 0}: blue, {15, 7}: blue}, [2]int{16, 8}},
+		{"cell size", [][]int{{1}, {3}}, Options{CellSize: 2},
+			map[[2]int]color.Color{{1, 1}: red, {0, 2}: blue}, [2]int{2, 4}},
+		{"unknown transparent", [][]int{{-1, 9}}, Options{CellSize: 1},
+			map[[2]int]color.Color{{0, 0}: color.Transparent, {1, 0}: color.Transparent}, [2]int{2, 1}},
+		{"unknown color", [][]int{{-1}}, Options{CellSize: 1, Unknown: white},
+			map[[2]int]color.Color{{0, 0}: white}, [2]int{1, 1}},
+		{"grid lines", [][]int{{1, 1}}, Options{CellSize: 4, GridLines: true, GridColor: "#0000ff"},
+			map[[2]int]color.Color{{0, 0}: blue, {4, 2}: blue, {2, 0}: blue, {1, 1}: red, {7, 3}: red}, [2]int{8, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Render(FormatPNG, tt.grid, testColors, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.size[0] || b.Dy() != tt.size[1] {
+				t.Errorf("image is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.size[0], tt.size[1])
+			}
+			for p, want := range tt.pixels {
+				r1, g1, b1, a1 := img.At(p[0], p[1]).RGBA()
+				r2, g2, b2, a2 := want.RGBA()
+				if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+					t.Errorf("pixel %v is %v, want %v", p, img.At(p[0], p[1]), want)
+				}
+			}
+		})
+	}
+}
+
+func TestSVG(t *testing.T) {
+	tests := []struct {
+		name string
+		grid [][]int
+		opts Options
+		// rects is the number of filled runs, lines the number of grid lines
+		rects, lines int
+	}{
+		{"runs", [][]int{{1, 1, 2, 2}, {0, 0, 0, 0}}, Options{}, 3, 0},
+		{"unknown", [][]int{{-1, 1, 9}}, Options{}, 1, 0},
+		{"grid lines", [][]int{{1, 2, 3}, {1, 2, 3}}, Options{GridLines: true}, 6, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Render(FormatSVG, tt.grid, testColors, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var svg struct {
+				Width int `xml:"width,attr"`
+				Rects []struct {
+					Fill string `xml:"fill,attr"`
+				} `xml:"rect"`
+				Lines []struct{} `xml:"g>line"`
+			}
+			if err := xml.Unmarshal(data, &svg); err != nil {
+				t.Fatal(err)
+			}
+			if svg.Width != len(tt.grid[0])*DefaultCellSize {
+				t.Errorf("width = %d, want %d", svg.Width, len(tt.grid[0])*DefaultCellSize)
+			}
+			if len(svg.Rects) != tt.rects || len(svg.Lines) != tt.lines {
+				t.Errorf("%d rects and %d lines, want %d and %d", len(svg.Rects), len(svg.Lines), tt.rects, tt.lines)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		grid   [][]int
+		colors []string
+		opts   Options
+	}{
+		{"format", "jpeg", [][]int{{0}}, testColors, Options{}},
+		{"empty png", FormatPNG, nil, testColors, Options{}},
+		{"empty svg", FormatSVG, [][]int{{}}, testColors, Options{}},
+		{"png color", FormatPNG, [][]int{{0}}, []string{"red"}, Options{}},
+		{"svg color", FormatSVG, [][]int{{0}}, []string{"#12345"}, Options{}},
+		{"grid color", FormatPNG, [][]int{{0}}, testColors, Options{GridColor: "#zzzzzz"}},
+		{"too many colors", FormatPNG, [][]int{{0}}, make([]string, 255), Options{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Render(tt.format, tt.grid, tt.colors, tt.opts); err == nil {
+				t.Error("Render() succeeded, want an error")
+			}
+		})
+	}
+}
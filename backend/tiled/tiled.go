@@ -0,0 +1,328 @@
+// Package tiled exports grids as maps for the Tiled editor, in TMX (XML) or
+// TMJ (JSON) format.
+package tiled
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"procedural-map-generation-toolkit/backend/render"
+)
+
+const (
+	FormatTMX = "tmx"
+	FormatTMJ = "tmj"
+)
+
+// Formats lists the supported export formats.
+var Formats = []string{FormatTMX, FormatTMJ}
+
+// Version is the Tiled map format version written.
+const Version = "1.10"
+
+// DefaultTileSize is the tile size in pixels of generated tilesets.
+const DefaultTileSize = 16
+
+// Layer is one tile layer. Values are tile types; values outside the
+// tileset mapping, e.g. -1, are left empty.
+type Layer struct {
+	Name string
+	Grid [][]int
+}
+
+// Tileset describes the tileset image a map refers to.
+type Tileset struct {
+	Name string
+	// Image is the path of the tileset image relative to the map file
+	Image                   string
+	ImageWidth, ImageHeight int
+	TileWidth, TileHeight   int
+	Columns, TileCount      int
+	// Mapping[t] is the tile index in the image for tile type t; nil maps
+	// every tile type to the index of the same number
+	Mapping []int
+}
+
+// ColorTileset builds a tileset of one solid tileSize square per color and
+// returns it with its PNG image, to be saved as ts.Image.
+func ColorTileset(colors []string, tileSize int) (Tileset, []byte, error) {
+	if tileSize <= 0 {
+		tileSize = DefaultTileSize
+	}
+	strip := make([]int, len(colors))
+	for i := range strip {
+		strip[i] = i
+	}
+	img, err := render.PNG([][]int{strip}, colors, render.Options{CellSize: tileSize})
+	if err != nil {
+		return Tileset{}, nil, err
+	}
+	ts := Tileset{
+		Name:        "tiles",
+		Image:       "tileset.png",
+		ImageWidth:  len(colors) * tileSize,
+		ImageHeight: tileSize,
+		TileWidth:   tileSize,
+		TileHeight:  tileSize,
+		Columns:     len(colors),
+		TileCount:   len(colors),
+	}
+	return ts, img, nil
+}
+
+// Map is a Tiled map of one or more equally sized layers sharing a tileset.
+type Map struct {
+	Width, Height int
+	Layers        []Layer
+	Tileset       Tileset
+}
+
+// New checks that the layers are non-empty, rectangular and of equal size.
+func New(layers []Layer, ts Tileset) (*Map, error) {
+	if len(layers) == 0 || len(layers[0].Grid) == 0 || len(layers[0].Grid[0]) == 0 {
+		return nil, errors.New("map has no tiles")
+	}
+	if ts.TileWidth <= 0 || ts.TileHeight <= 0 || ts.TileCount <= 0 || ts.Columns <= 0 {
+		return nil, errors.New("tileset needs a tile size, count and columns")
+	}
+	for _, idx := range ts.Mapping {
+		if idx < -1 || idx >= ts.TileCount {
+			return nil, fmt.Errorf("tileset mapping index %d outside 0..%d", idx, ts.TileCount-1)
+		}
+	}
+	m := &Map{Width: len(layers[0].Grid[0]), Height: len(layers[0].Grid), Layers: layers, Tileset: ts}
+	for i, l := range layers {
+		if len(l.Grid) != m.Height {
+			return nil, fmt.Errorf("layer %d has %d rows, want %d", i, len(l.Grid), m.Height)
+		}
+		for _, row := range l.Grid {
+			if len(row) != m.Width {
+				return nil, fmt.Errorf("layer %d rows differ in length", i)
+			}
+		}
+	}
+	return m, nil
+}
+
+// gids returns the global tile ids of layer l in row-major order; 0 is empty.
+func (m *Map) gids(l Layer) []int {
+	out := make([]int, 0, m.Width*m.Height)
+	for _, row := range l.Grid {
+		for _, v := range row {
+			idx := v
+			if m.Tileset.Mapping != nil {
+				idx = -1
+				if v >= 0 && v < len(m.Tileset.Mapping) {
+					idx = m.Tileset.Mapping[v]
+				}
+			}
+			if idx < 0 || idx >= m.Tileset.TileCount {
+				out = append(out, 0)
+				continue
+			}
+			// The only tileset starts at gid 1
+			out = append(out, idx+1)
+		}
+	}
+	return out
+}
+
+func (m *Map) layerName(i int) string {
+	if name := m.Layers[i].Name; name != "" {
+		return name
+	}
+	return "layer " + strconv.Itoa(i+1)
+}
+
+type tmxMap struct {
+	XMLName      xml.Name   `xml:"map"`
+	Version      string     `xml:"version,attr"`
+	Orientation  string     `xml:"orientation,attr"`
+	RenderOrder  string     `xml:"renderorder,attr"`
+	Width        int        `xml:"width,attr"`
+	Height       int        `xml:"height,attr"`
+	TileWidth    int        `xml:"tilewidth,attr"`
+	TileHeight   int        `xml:"tileheight,attr"`
+	Infinite     int        `xml:"infinite,attr"`
+	NextLayerID  int        `xml:"nextlayerid,attr"`
+	NextObjectID int        `xml:"nextobjectid,attr"`
+	Tileset      tmxTileset `xml:"tileset"`
+	Layers       []tmxLayer `xml:"layer"`
+}
+
+type tmxTileset struct {
+	FirstGID   int      `xml:"firstgid,attr"`
+	Name       string   `xml:"name,attr"`
+	TileWidth  int      `xml:"tilewidth,attr"`
+	TileHeight int      `xml:"tileheight,attr"`
+	TileCount  int      `xml:"tilecount,attr"`
+	Columns    int      `xml:"columns,attr"`
+	Image      tmxImage `xml:"image"`
+}
+
+type tmxImage struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+type tmxLayer struct {
+	ID     int     `xml:"id,attr"`
+	Name   string  `xml:"name,attr"`
+	Width  int     `xml:"width,attr"`
+	Height int     `xml:"height,attr"`
+	Data   tmxData `xml:"data"`
+}
+
+type tmxData struct {
+	Encoding string `xml:"encoding,attr"`
+	// Digits and commas only, written as is to keep the line breaks
+	CSV string `xml:",innerxml"`
+}
+
+// TMX encodes m as a Tiled XML map with CSV layer data.
+func (m *Map) TMX() ([]byte, error) {
+	ts := m.Tileset
+	out := tmxMap{
+		Version: Version, Orientation: "orthogonal", RenderOrder: "right-down",
+		Width: m.Width, Height: m.Height, TileWidth: ts.TileWidth, TileHeight: ts.TileHeight,
+		NextLayerID: len(m.Layers) + 1, NextObjectID: 1,
+		Tileset: tmxTileset{
+			FirstGID: 1, Name: ts.Name, TileWidth: ts.TileWidth, TileHeight: ts.TileHeight,
+			TileCount: ts.TileCount, Columns: ts.Columns,
+			Image: tmxImage{Source: ts.Image, Width: ts.ImageWidth, Height: ts.ImageHeight},
+		},
+	}
+	for i, l := range m.Layers {
+		gids := m.gids(l)
+		var csv strings.Builder
+		csv.WriteString("\n")
+		for y := 0; y < m.Height; y++ {
+			for x := 0; x < m.Width; x++ {
+				csv.WriteString(strconv.Itoa(gids[y*m.Width+x]))
+				if y < m.Height-1 || x < m.Width-1 {
+					csv.WriteString(",")
+				}
+			}
+			csv.WriteString("\n")
+		}
+		out.Layers = append(out.Layers, tmxLayer{
+			ID: i + 1, Name: m.layerName(i), Width: m.Width, Height: m.Height,
+			Data: tmxData{Encoding: "csv", CSV: csv.String()},
+		})
+	}
+	data, err := xml.MarshalIndent(out, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+type tmjMap struct {
+	Type             string       `json:"type"`
+	Version          string       `json:"version"`
+	Orientation      string       `json:"orientation"`
+	RenderOrder      string       `json:"renderorder"`
+	Width            int          `json:"width"`
+	Height           int          `json:"height"`
+	TileWidth        int          `json:"tilewidth"`
+	TileHeight       int          `json:"tileheight"`
+	Infinite         bool         `json:"infinite"`
+	NextLayerID      int          `json:"nextlayerid"`
+	NextObjectID     int          `json:"nextobjectid"`
+	CompressionLevel int          `json:"compressionlevel"`
+	Layers           []tmjLayer   `json:"layers"`
+	Tilesets         []tmjTileset `json:"tilesets"`
+}
+
+type tmjLayer struct {
+	ID      int     `json:"id"`
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Opacity float64 `json:"opacity"`
+	Visible bool    `json:"visible"`
+	Data    []int   `json:"data"`
+}
+
+type tmjTileset struct {
+	FirstGID    int    `json:"firstgid"`
+	Name        string `json:"name"`
+	Image       string `json:"image"`
+	ImageWidth  int    `json:"imagewidth"`
+	ImageHeight int    `json:"imageheight"`
+	TileWidth   int    `json:"tilewidth"`
+	TileHeight  int    `json:"tileheight"`
+	TileCount   int    `json:"tilecount"`
+	Columns     int    `json:"columns"`
+	Margin      int    `json:"margin"`
+	Spacing     int    `json:"spacing"`
+}
+
+// TMJ encodes m as a Tiled JSON map.
+func (m *Map) TMJ() ([]byte, error) {
+	ts := m.Tileset
+	out := tmjMap{
+		Type: "map", Version: Version, Orientation: "orthogonal", RenderOrder: "right-down",
+		Width: m.Width, Height: m.Height, TileWidth: ts.TileWidth, TileHeight: ts.TileHeight,
+		NextLayerID: len(m.Layers) + 1, NextObjectID: 1, CompressionLevel: -1,
+		Tilesets: []tmjTileset{{
+			FirstGID: 1, Name: ts.Name, Image: ts.Image, ImageWidth: ts.ImageWidth, ImageHeight: ts.ImageHeight,
+			TileWidth: ts.TileWidth, TileHeight: ts.TileHeight, TileCount: ts.TileCount, Columns: ts.Columns,
+		}},
+	}
+	for i, l := range m.Layers {
+		out.Layers = append(out.Layers, tmjLayer{
+			ID: i + 1, Name: m.layerName(i), Type: "tilelayer", Width: m.Width, Height: m.Height,
+			Opacity: 1, Visible: true, Data: m.gids(l),
+		})
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// Encode returns m in the given format, "tmx" or "tmj".
+func (m *Map) Encode(format string) ([]byte, error) {
+	switch format {
+	case FormatTMX:
+		return m.TMX()
+	case FormatTMJ:
+		return m.TMJ()
+	}
+	return nil, fmt.Errorf("unknown Tiled format %q", format)
+}
+
+// Zip packs the map file name.format with the tileset image, so the
+// archive opens in Tiled as is.
+func (m *Map) Zip(name, format string, tilesetImage []byte) ([]byte, error) {
+	data, err := m.Encode(format)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{name + "." + format, data}, {m.Tileset.Image, tilesetImage}} {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
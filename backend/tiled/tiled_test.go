@@ -0,0 +1,181 @@
+package tiled
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"image/png"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// decodeTMX parses a TMX map and returns it with the gids of every layer.
+func decodeTMX(t *testing.T, data []byte) (tmxMap, [][]int) {
+	t.Helper()
+	var m tmxMap
+	if err := xml.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	var layers [][]int
+	for _, l := range m.Layers {
+		if l.Data.Encoding != "csv" {
+			t.Fatalf("layer encoding %q, want csv", l.Data.Encoding)
+		}
+		var gids []int
+		for _, s := range strings.Split(strings.TrimSpace(l.Data.CSV), ",") {
+			gid, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			gids = append(gids, gid)
+		}
+		layers = append(layers, gids)
+	}
+	return m, layers
+}
+
+func TestEncode(t *testing.T) {
+	colors := tiles.Default.Colors()
+	ts, _, err := ColorTileset(colors, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped := ts
+	mapped.Mapping = []int{7, 6, 5, 4, 3, 2, 1, -1}
+	tests := []struct {
+		name    string
+		layers  []Layer
+		tileset Tileset
+		// gids per layer in row-major order
+		want  [][]int
+		names []string
+	}{
+		{"identity", []Layer{{Name: "terrain", Grid: [][]int{{0, 1, 2}, {5, 6, 7}}}}, ts,
+			[][]int{{1, 2, 3, 6, 7, 8}}, []string{"terrain"}},
+		{"unpainted and unknown", []Layer{{Grid: [][]int{{-1, 8}, {3, 100}}}}, ts,
+			[][]int{{0, 0, 4, 0}}, []string{"layer 1"}},
+		{"mapping", []Layer{{Grid: [][]int{{0, 6, 7, 9}}}}, mapped,
+			[][]int{{8, 2, 0, 0}}, []string{"layer 1"}},
+		{"layers", []Layer{{Name: "a", Grid: [][]int{{0}, {1}}}, {Grid: [][]int{{2}, {-1}}}}, ts,
+			[][]int{{1, 2}, {3, 0}}, []string{"a", "layer 2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.layers, tt.tileset)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := m.Encode(FormatTMX)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmx, gids := decodeTMX(t, data)
+			if !reflect.DeepEqual(gids, tt.want) {
+				t.Errorf("TMX gids = %v, want %v", gids, tt.want)
+			}
+			if tmx.Width != m.Width || tmx.Height != m.Height || tmx.Tileset.FirstGID != 1 || tmx.Tileset.TileCount != len(colors) {
+				t.Errorf("TMX map %dx%d with tileset %+v", tmx.Width, tmx.Height, tmx.Tileset)
+			}
+			for i, l := range tmx.Layers {
+				if l.Name != tt.names[i] || l.ID != i+1 {
+					t.Errorf("TMX layer %d is %q with id %d, want %q", i, l.Name, l.ID, tt.names[i])
+				}
+			}
+
+			data, err = m.Encode(FormatTMJ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tmj tmjMap
+			if err := json.Unmarshal(data, &tmj); err != nil {
+				t.Fatal(err)
+			}
+			if tmj.Type != "map" || tmj.Width != m.Width || tmj.Height != m.Height || len(tmj.Tilesets) != 1 {
+				t.Errorf("TMJ map %q %dx%d with %d tilesets", tmj.Type, tmj.Width, tmj.Height, len(tmj.Tilesets))
+			}
+			gids = nil
+			for i, l := range tmj.Layers {
+				gids = append(gids, l.Data)
+				if l.Name != tt.names[i] || l.Type != "tilelayer" {
+					t.Errorf("TMJ layer %d is %q of type %q, want %q", i, l.Name, l.Type, tt.names[i])
+				}
+			}
+			if !reflect.DeepEqual(gids, tt.want) {
+				t.Errorf("TMJ gids = %v, want %v", gids, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	ts, _, err := ColorTileset([]string{"#000000", "#ffffff"}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badMapping := ts
+	badMapping.Mapping = []int{0, 2}
+	tests := []struct {
+		name    string
+		layers  []Layer
+		tileset Tileset
+	}{
+		{"no layers", nil, ts},
+		{"empty grid", []Layer{{Grid: [][]int{}}}, ts},
+		{"no tile size", []Layer{{Grid: [][]int{{0}}}}, Tileset{TileCount: 1, Columns: 1}},
+		{"mapping", []Layer{{Grid: [][]int{{0}}}}, badMapping},
+		{"ragged", []Layer{{Grid: [][]int{{0, 1}, {0}}}}, ts},
+		{"layer sizes", []Layer{{Grid: [][]int{{0}}}, {Grid: [][]int{{0}, {1}}}}, ts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.layers, tt.tileset); err == nil {
+				t.Error("New() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestZip(t *testing.T) {
+	colors := []string{"#000000", "#ff0000", "#00ff00"}
+	ts, img, err := ColorTileset(colors, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := New([]Layer{{Grid: [][]int{{0, 1, 2}}}}, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.Zip("map", FormatTMJ, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if want := []string{"map.tmj", "tileset.png"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("zip holds %v, want %v", names, want)
+	}
+	rc, err := zr.File[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	tileset, err := png.Decode(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := tileset.Bounds(); b.Dx() != ts.ImageWidth || b.Dy() != ts.ImageHeight || b.Dx() != 12 {
+		t.Errorf("tileset image is %dx%d, want %dx%d", b.Dx(), b.Dy(), ts.ImageWidth, ts.ImageHeight)
+	}
+}
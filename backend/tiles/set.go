@@ -0,0 +1,299 @@
+package tiles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Category groups tiles by what they represent. Generators and metrics use
+// it instead of fixed tile types, e.g. WFC puts water on the map border.
+// Sets may use categories of their own, such as "wall" or "road".
+type Category string
+
+const (
+	CategoryWater      Category = "water"
+	CategoryShore      Category = "shore"
+	CategoryLand       Category = "land"
+	CategoryVegetation Category = "vegetation"
+	CategoryMountain   Category = "mountain"
+)
+
+// Tile is one tile type of a set. Grids hold the ID, which is the index of
+// the tile in its set.
+type Tile struct {
+	ID       TileType `json:"id"`
+	Name     string   `json:"name"`
+	Color    string   `json:"color"`
+	Category Category `json:"category"`
+	// Elevation is the highest normalized noise value (0..1] the noise
+	// generator maps to the tile; tiles without one are left out, and sets
+	// without any split the range evenly
+	Elevation float64 `json:"elevation,omitempty"`
+	// Neighbors lists the tiles WFC may place next to this one; nil allows
+	// every tile
+	Neighbors []TileType `json:"neighbors,omitempty"`
+}
+
+// Set is a list of tile types defined at runtime.
+type Set struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Tiles       []Tile `json:"tiles"`
+	// Defaults overrides the default tile of request fields such as
+	// "wallTile" or "boundaryTile" for this set
+	Defaults map[string]TileType `json:"defaults,omitempty"`
+}
+
+// MaxTiles bounds the number of tiles in a set, leaving room in an 8-bit
+// image palette for the unknown and grid line colors.
+const MaxTiles = 254
+
+// Len returns the number of tile types.
+func (s *Set) Len() int { return len(s.Tiles) }
+
+// Valid reports whether t is a tile type of s.
+func (s *Set) Valid(t int) bool { return t >= 0 && t < len(s.Tiles) }
+
+// Colors returns the color of every tile type, indexed by id.
+func (s *Set) Colors() []string {
+	colors := make([]string, len(s.Tiles))
+	for i, t := range s.Tiles {
+		colors[i] = t.Color
+	}
+	return colors
+}
+
+// OfCategory returns the tiles of the given categories in id order.
+func (s *Set) OfCategory(categories ...Category) []TileType {
+	var out []TileType
+	for _, t := range s.Tiles {
+		for _, c := range categories {
+			if t.Category == c {
+				out = append(out, t.ID)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// IsWater reports whether t is a water tile.
+func (s *Set) IsWater(t int) bool {
+	return s.Valid(t) && s.Tiles[t].Category == CategoryWater
+}
+
+// IsLand reports whether t is a land tile, i.e. any tile outside the water
+// category, shore included. metrics.LandRatio counts these.
+func (s *Set) IsLand(t int) bool {
+	return s.Valid(t) && s.Tiles[t].Category != CategoryWater
+}
+
+// Land returns the land tiles in id order, see IsLand.
+func (s *Set) Land() []TileType {
+	var out []TileType
+	for _, t := range s.Tiles {
+		if s.IsLand(int(t.ID)) {
+			out = append(out, t.ID)
+		}
+	}
+	return out
+}
+
+// IsInland reports whether t is a land tile away from the water's edge, i.e.
+// neither water nor shore.
+func (s *Set) IsInland(t int) bool {
+	return s.IsLand(t) && s.Tiles[t].Category != CategoryShore
+}
+
+// Inland returns the inland tiles in id order, see IsInland.
+func (s *Set) Inland() []TileType {
+	var out []TileType
+	for _, t := range s.Tiles {
+		if s.IsInland(int(t.ID)) {
+			out = append(out, t.ID)
+		}
+	}
+	return out
+}
+
+// Neighbors returns the tiles WFC may place next to t.
+func (s *Set) Neighbors(t TileType) []TileType {
+	if n := s.Tiles[t].Neighbors; n != nil {
+		return n
+	}
+	all := make([]TileType, len(s.Tiles))
+	for i := range all {
+		all[i] = TileType(i)
+	}
+	return all
+}
+
+// Default returns the set's default for a tile-typed request field, or def
+// when the set has none. def falls back to tile 0 when it is not in the set.
+func (s *Set) Default(field string, def TileType) TileType {
+	if t, ok := s.Defaults[field]; ok {
+		return t
+	}
+	if s.Valid(int(def)) {
+		return def
+	}
+	return 0
+}
+
+// Validate checks that s is usable by the generators.
+func (s *Set) Validate() error {
+	if s.Name == "" {
+		return errors.New("tile set has no name")
+	}
+	if len(s.Tiles) == 0 || len(s.Tiles) > MaxTiles {
+		return fmt.Errorf("tile set %q must have 1 to %d tiles", s.Name, MaxTiles)
+	}
+	for i, t := range s.Tiles {
+		if int(t.ID) != i {
+			return fmt.Errorf("tile set %q: tile %d has id %d, ids must count up from 0", s.Name, i, t.ID)
+		}
+		if t.Name == "" {
+			return fmt.Errorf("tile set %q: tile %d has no name", s.Name, i)
+		}
+		if !validColor(t.Color) {
+			return fmt.Errorf("tile set %q: tile %d has invalid color %q", s.Name, i, t.Color)
+		}
+		if t.Elevation < 0 || t.Elevation > 1 {
+			return fmt.Errorf("tile set %q: tile %d elevation must be between 0 and 1", s.Name, i)
+		}
+		for _, n := range t.Neighbors {
+			if !s.Valid(int(n)) {
+				return fmt.Errorf("tile set %q: tile %d has unknown neighbor %d", s.Name, i, n)
+			}
+		}
+	}
+	for field, t := range s.Defaults {
+		if !s.Valid(int(t)) {
+			return fmt.Errorf("tile set %q: default %s %d is not a tile", s.Name, field, t)
+		}
+	}
+	return nil
+}
+
+// validColor reports whether c is "#rrggbb".
+func validColor(c string) bool {
+	if len(c) != 7 || c[0] != '#' {
+		return false
+	}
+	_, err := strconv.ParseUint(c[1:], 16, 32)
+	return err == nil
+}
+
+// Default is the coastal terrain set of the TileType constants.
+var Default = &Set{
+	Name:        "terrain",
+	Description: "Coastal terrain from deep water to forest",
+	Tiles: []Tile{
+		{ID: DeepWater, Name: "Deep water", Color: TileColors[DeepWater], Category: CategoryWater, Elevation: 0.2,
+			Neighbors: []TileType{DeepWater, Water, CoastalWater}},
+		{ID: Water, Name: "Water", Color: TileColors[Water], Category: CategoryWater, Elevation: 0.4,
+			Neighbors: []TileType{DeepWater, Water, CoastalWater, WetSand}},
+		{ID: CoastalWater, Name: "Coastal water", Color: TileColors[CoastalWater], Category: CategoryWater, Elevation: 0.5,
+			Neighbors: []TileType{DeepWater, Water, CoastalWater, WetSand, Sand}},
+		{ID: WetSand, Name: "Wet sand", Color: TileColors[WetSand], Category: CategoryShore, Elevation: 0.55,
+			Neighbors: []TileType{Water, CoastalWater, WetSand, Sand, Grass}},
+		{ID: Sand, Name: "Sand", Color: TileColors[Sand], Category: CategoryLand, Elevation: 0.6,
+			Neighbors: []TileType{CoastalWater, WetSand, Sand, Grass, Bushes}},
+		{ID: Grass, Name: "Grass", Color: TileColors[Grass], Category: CategoryVegetation, Elevation: 0.7,
+			Neighbors: []TileType{WetSand, Sand, Grass, Bushes, Forest}},
+		{ID: Bushes, Name: "Bushes", Color: TileColors[Bushes], Category: CategoryVegetation, Elevation: 0.8,
+			Neighbors: []TileType{Sand, Grass, Bushes, Forest}},
+		{ID: Forest, Name: "Forest", Color: TileColors[Forest], Category: CategoryVegetation, Elevation: 1.0,
+			Neighbors: []TileType{Grass, Bushes, Forest}},
+	},
+}
+
+func init() {
+	if err := Register(Default); err != nil {
+		panic(err)
+	}
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]*Set{}
+)
+
+// Register validates s and adds it to the registry. Registered sets must
+// not be modified.
+func Register(s *Set) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := registry[s.Name]; dup {
+		return fmt.Errorf("tile set %q registered twice", s.Name)
+	}
+	registry[s.Name] = s
+	return nil
+}
+
+// Lookup returns the set registered under name; the empty name is Default.
+func Lookup(name string) (*Set, bool) {
+	if name == "" {
+		return Default, true
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := registry[name]
+	return s, ok
+}
+
+// All returns the registered sets sorted by name.
+func All() []*Set {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make([]*Set, 0, len(registry))
+	for _, s := range registry {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Names returns the names of the registered sets, sorted.
+func Names() []string {
+	all := All()
+	names := make([]string, len(all))
+	for i, s := range all {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// LoadDir registers the set in every .json file of dir and returns their
+// names.
+func LoadDir(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return names, err
+		}
+		s := new(Set)
+		if err := json.Unmarshal(data, s); err != nil {
+			return names, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := Register(s); err != nil {
+			return names, fmt.Errorf("%s: %w", path, err)
+		}
+		names = append(names, s.Name)
+	}
+	return names, nil
+}
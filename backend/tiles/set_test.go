@@ -0,0 +1,131 @@
+package tiles
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSetValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		// err is a substring of the expected error; empty means valid
+		err string
+	}{
+		{"valid", `{"name": "roads", "tiles": [
+			{"id": 0, "name": "Grass", "color": "#4caf50", "category": "vegetation", "elevation": 0.6},
+			{"id": 1, "name": "Road", "color": "#616161", "category": "road", "neighbors": [0, 1]}],
+			"defaults": {"aliveTile": 1}}`, ""},
+		{"no name", `{"tiles": [{"id": 0, "name": "A", "color": "#000000"}]}`, "has no name"},
+		{"no tiles", `{"name": "empty", "tiles": []}`, "must have 1 to 254 tiles"},
+		{"ids out of order", `{"name": "s", "tiles": [{"id": 1, "name": "A", "color": "#000000"}]}`, "ids must count up from 0"},
+		{"tile without name", `{"name": "s", "tiles": [{"id": 0, "color": "#000000"}]}`, "tile 0 has no name"},
+		{"short color", `{"name": "s", "tiles": [{"id": 0, "name": "A", "color": "#fff"}]}`, `invalid color "#fff"`},
+		{"color name", `{"name": "s", "tiles": [{"id": 0, "name": "A", "color": "#blackk"}]}`, "invalid color"},
+		{"elevation", `{"name": "s", "tiles": [{"id": 0, "name": "A", "color": "#000000", "elevation": 1.5}]}`, "elevation must be between 0 and 1"},
+		{"unknown neighbor", `{"name": "s", "tiles": [{"id": 0, "name": "A", "color": "#000000", "neighbors": [0, 3]}]}`, "unknown neighbor 3"},
+		{"unknown default", `{"name": "s", "tiles": [{"id": 0, "name": "A", "color": "#000000"}], "defaults": {"wallTile": 2}}`, "default wallTile 2 is not a tile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(Set)
+			if err := json.Unmarshal([]byte(tt.json), s); err != nil {
+				t.Fatal(err)
+			}
+			err := s.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("Validate() = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDefaultSet(t *testing.T) {
+	if err := Default.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if Default.Len() != int(NumTileTypes) {
+		t.Errorf("Default has %d tiles, want %d", Default.Len(), NumTileTypes)
+	}
+	if s, ok := Lookup(""); !ok || s != Default {
+		t.Errorf(`Lookup("") = %v, %v, want Default`, s, ok)
+	}
+	for i, tile := range Default.Tiles {
+		if tile.Color != TileColors[i] {
+			t.Errorf("tile %d color = %s, want %s", i, tile.Color, TileColors[i])
+		}
+	}
+}
+
+func TestLand(t *testing.T) {
+	// Shore is land but not inland
+	if got, want := Default.Land(), []TileType{WetSand, Sand, Grass, Bushes, Forest}; !slices.Equal(got, want) {
+		t.Errorf("Land() = %v, want %v", got, want)
+	}
+	if got, want := Default.Inland(), []TileType{Sand, Grass, Bushes, Forest}; !slices.Equal(got, want) {
+		t.Errorf("Inland() = %v, want %v", got, want)
+	}
+	for _, tile := range []int{-1, int(NumTileTypes)} {
+		if Default.IsLand(tile) || Default.IsInland(tile) {
+			t.Errorf("tile %d outside the set counts as land", tile)
+		}
+	}
+}
+
+func writeSets(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadDir(t *testing.T) {
+	// The example sets shipped with the repository
+	names, err := LoadDir(filepath.Join("..", "..", "tilesets"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "alpine,dungeon" {
+		t.Errorf("LoadDir(tilesets) = %v, want [alpine dungeon]", names)
+	}
+	dungeon, ok := Lookup("dungeon")
+	if !ok {
+		t.Fatal("dungeon not registered")
+	}
+	if got := dungeon.Default("floorTile", 0); got != 1 {
+		t.Errorf("dungeon floorTile = %d, want 1", got)
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{"not json", map[string]string{"a.json": "{"}, "a.json"},
+		{"invalid set", map[string]string{"b.json": `{"name": "load-invalid", "tiles": []}`}, "must have 1 to 254 tiles"},
+		{"built-in name", map[string]string{"c.json": `{"name": "terrain", "tiles": [{"id": 0, "name": "A", "color": "#000000"}]}`}, "registered twice"},
+		{"other files", map[string]string{"d.txt": "{"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadDir(writeSets(t, tt.files))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("LoadDir() = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("LoadDir() = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package tiles
 
-// TileType is the single source of truth for all tile‐color constants.
+// TileType is a tile of a Set, identified by its index. The constants below
+// are the tiles of Default.
 type TileType int
 
 const (
@@ -12,9 +13,10 @@ const (
 	Grass
 	Bushes
 	Forest
-	NumTileTypes // for WFC loops
+	NumTileTypes // number of tiles in Default
 )
 
+// TileColors are the colors of Default.
 var TileColors = [NumTileTypes]string{
 	DeepWater:    "#00507f",
 	Water:        "#1085bc",
@@ -0,0 +1,108 @@
+package timelapse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"image/gif"
+
+	"procedural-map-generation-toolkit/backend/render"
+)
+
+const (
+	EncodingFull  = "full"  // every frame as a complete grid
+	EncodingDelta = "delta" // first frame complete, then changed cells only
+	EncodingGIF   = "gif"   // server-rendered animated GIF
+)
+
+const (
+	GIFCellSize = 8      // pixels per tile
+	gifDelay    = 20     // hundredths of a second per frame
+	maxGIFSide  = 0xffff // largest GIF width or height
+)
+
+// Delta is a single changed cell: x, y and the new tile.
+type Delta [3]int
+
+// Timelapse is the recorded evolution of a grid.
+type Timelapse struct {
+	Every    int       `json:"every"`
+	Encoding string    `json:"encoding"`
+	Frames   [][][]int `json:"frames,omitempty"`
+	// Deltas[i] turns frame i into frame i+1 (delta encoding only)
+	Deltas [][]Delta `json:"deltas,omitempty"`
+	// GIF is a base64-encoded animated GIF (gif encoding only)
+	GIF string `json:"gif,omitempty"`
+}
+
+// Build encodes recorded frames. An empty encoding means EncodingFull.
+func Build(frames [][][]int, every int, encoding string, colors []string) (*Timelapse, error) {
+	t := &Timelapse{Every: every, Encoding: encoding}
+	switch encoding {
+	case "", EncodingFull:
+		t.Encoding = EncodingFull
+		t.Frames = frames
+	case EncodingDelta:
+		if len(frames) > 0 {
+			t.Frames = frames[:1]
+		}
+		t.Deltas = Deltas(frames)
+	case EncodingGIF:
+		data, err := GIF(frames, colors, GIFCellSize, gifDelay)
+		if err != nil {
+			return nil, err
+		}
+		t.GIF = base64.StdEncoding.EncodeToString(data)
+	default:
+		return nil, fmt.Errorf("unknown snapshot encoding %q", encoding)
+	}
+	return t, nil
+}
+
+// Deltas returns the changed cells between consecutive frames.
+func Deltas(frames [][][]int) [][]Delta {
+	if len(frames) < 2 {
+		return nil
+	}
+	out := make([][]Delta, len(frames)-1)
+	for i := 1; i < len(frames); i++ {
+		prev, cur := frames[i-1], frames[i]
+		changes := []Delta{}
+		for y := range cur {
+			for x := range cur[y] {
+				if cur[y][x] != prev[y][x] {
+					changes = append(changes, Delta{x, y, cur[y][x]})
+				}
+			}
+		}
+		out[i-1] = changes
+	}
+	return out
+}
+
+// GIF renders the frames as an animated GIF with one palette entry per tile color.
+func GIF(frames [][][]int, colors []string, cellSize, delay int) ([]byte, error) {
+	if len(frames) == 0 || len(frames[0]) == 0 {
+		return nil, fmt.Errorf("no frames to encode")
+	}
+	if len(frames[0][0])*cellSize > maxGIFSide || len(frames[0])*cellSize > maxGIFSide {
+		return nil, fmt.Errorf("gif frames must not exceed %d pixels per side", maxGIFSide)
+	}
+	anim := &gif.GIF{}
+	for _, frame := range frames {
+		// Black for values outside the palette
+		img, err := render.Image(frame, colors, render.Options{CellSize: cellSize, Unknown: color.Black})
+		if err != nil {
+			return nil, err
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
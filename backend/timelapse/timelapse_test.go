@@ -0,0 +1,171 @@
+package timelapse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/color"
+	"image/gif"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/render"
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// randomFrames returns n width x height frames that change a few cells each.
+func randomFrames(n, width, height int, seed int64) [][][]int {
+	rng := rand.New(rand.NewSource(seed))
+	frames := make([][][]int, n)
+	for i := range frames {
+		frames[i] = make([][]int, height)
+		for y := range frames[i] {
+			if i == 0 {
+				frames[i][y] = make([]int, width)
+				for x := range frames[i][y] {
+					frames[i][y][x] = rng.Intn(int(tiles.NumTileTypes))
+				}
+			} else {
+				frames[i][y] = append([]int(nil), frames[i-1][y]...)
+			}
+		}
+		for c := 0; i > 0 && c < i; c++ {
+			frames[i][rng.Intn(height)][rng.Intn(width)] = rng.Intn(int(tiles.NumTileTypes))
+		}
+	}
+	return frames
+}
+
+func TestDeltas(t *testing.T) {
+	tests := []struct {
+		frames, width, height int
+	}{
+		{1, 4, 4},
+		{2, 4, 4},
+		{10, 16, 8},
+		{30, 5, 20},
+	}
+	for _, tt := range tests {
+		frames := randomFrames(tt.frames, tt.width, tt.height, int64(tt.frames))
+		deltas := Deltas(frames)
+		if tt.frames < 2 {
+			if deltas != nil {
+				t.Errorf("%d frames: %d deltas, want none", tt.frames, len(deltas))
+			}
+			continue
+		}
+		if len(deltas) != tt.frames-1 {
+			t.Fatalf("%d frames: %d deltas", tt.frames, len(deltas))
+		}
+		// Replaying the deltas onto the first frame gives every frame
+		grid := make([][]int, tt.height)
+		for y := range grid {
+			grid[y] = append([]int(nil), frames[0][y]...)
+		}
+		for i, changes := range deltas {
+			for _, d := range changes {
+				grid[d[1]][d[0]] = d[2]
+			}
+			if !reflect.DeepEqual(grid, frames[i+1]) {
+				t.Errorf("%d frames: frame %d differs after replaying its deltas", tt.frames, i+1)
+			}
+		}
+	}
+}
+
+func TestGIF(t *testing.T) {
+	colors := tiles.Default.Colors()
+	palette, err := render.Palette(colors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		frames, width, height, cellSize int
+	}{
+		{1, 3, 2, 1},
+		{5, 16, 12, 4},
+		{3, 100, 80, 4},
+	}
+	for _, tt := range tests {
+		frames := randomFrames(tt.frames, tt.width, tt.height, 1)
+		frames[0][0][0] = -1 // drawn black
+		data, err := GIF(frames, colors, tt.cellSize, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%d frames of %dx%d: %v", tt.frames, tt.width, tt.height, err)
+		}
+		if len(g.Image) != tt.frames {
+			t.Errorf("%d frames, want %d", len(g.Image), tt.frames)
+		}
+		// A single frame is a still image
+		if tt.frames > 1 && g.LoopCount != 0 {
+			t.Errorf("loop count %d, want the animation to loop forever", g.LoopCount)
+		}
+		for i, img := range g.Image {
+			if b := img.Bounds(); b.Dx() != tt.width*tt.cellSize || b.Dy() != tt.height*tt.cellSize {
+				t.Fatalf("frame %d is %dx%d", i, b.Dx(), b.Dy())
+			}
+			if g.Delay[i] != 7 {
+				t.Errorf("frame %d delay %d, want 7", i, g.Delay[i])
+			}
+			for y := range frames[i] {
+				for x, v := range frames[i][y] {
+					want := color.Color(color.Black)
+					if v >= 0 {
+						want = palette[v]
+					}
+					if !sameColor(img.At(x*tt.cellSize, y*tt.cellSize), want) {
+						t.Fatalf("frame %d cell (%d, %d) is %v, want %v", i, x, y, img.At(x*tt.cellSize, y*tt.cellSize), want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGIFErrors(t *testing.T) {
+	colors := tiles.Default.Colors()
+	wide := [][][]int{{make([]int, 9000)}}
+	for name, frames := range map[string][][][]int{"no frames": nil, "too wide": wide} {
+		if _, err := GIF(frames, colors, 8, 1); err == nil {
+			t.Errorf("%s: GIF succeeded, want an error", name)
+		}
+	}
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func TestBuild(t *testing.T) {
+	frames := randomFrames(4, 6, 5, 2)
+	tests := []struct {
+		encoding string
+		check    func(*Timelapse) bool
+	}{
+		{"", func(tl *Timelapse) bool { return tl.Encoding == EncodingFull && len(tl.Frames) == 4 }},
+		{EncodingFull, func(tl *Timelapse) bool { return len(tl.Frames) == 4 && tl.Deltas == nil }},
+		{EncodingDelta, func(tl *Timelapse) bool { return len(tl.Frames) == 1 && len(tl.Deltas) == 3 }},
+		{EncodingGIF, func(tl *Timelapse) bool {
+			data, err := base64.StdEncoding.DecodeString(tl.GIF)
+			return err == nil && tl.Frames == nil && bytes.HasPrefix(data, []byte("GIF89a"))
+		}},
+	}
+	for _, tt := range tests {
+		tl, err := Build(frames, 3, tt.encoding, tiles.Default.Colors())
+		if err != nil {
+			t.Fatalf("encoding %q: %v", tt.encoding, err)
+		}
+		if tl.Every != 3 || !tt.check(tl) {
+			t.Errorf("encoding %q: unexpected time-lapse %+v", tt.encoding, tl)
+		}
+	}
+	if _, err := Build(frames, 1, "mp4", nil); err == nil {
+		t.Error(`Build with encoding "mp4" succeeded`)
+	}
+}
@@ -1,24 +1,14 @@
 package wfc
 
 import (
+	"context"
 	"errors"
 	"math/rand"
 	"procedural-map-generation-toolkit/backend/tiles"
+	"slices"
 	"sort"
 )
 
-// adjacencyRules defines allowed neighbors for each tile.
-var adjacencyRules = map[tiles.TileType][]tiles.TileType{
-	tiles.DeepWater:    {tiles.DeepWater, tiles.Water, tiles.CoastalWater},
-	tiles.Water:        {tiles.DeepWater, tiles.Water, tiles.CoastalWater, tiles.WetSand},
-	tiles.CoastalWater: {tiles.DeepWater, tiles.Water, tiles.CoastalWater, tiles.WetSand, tiles.Sand},
-	tiles.WetSand:      {tiles.Water, tiles.CoastalWater, tiles.WetSand, tiles.Sand, tiles.Grass},
-	tiles.Sand:         {tiles.CoastalWater, tiles.WetSand, tiles.Sand, tiles.Grass, tiles.Bushes},
-	tiles.Grass:        {tiles.WetSand, tiles.Sand, tiles.Grass, tiles.Bushes, tiles.Forest},
-	tiles.Bushes:       {tiles.Sand, tiles.Grass, tiles.Bushes, tiles.Forest},
-	tiles.Forest:       {tiles.Grass, tiles.Bushes, tiles.Forest},
-}
-
 type Cell struct {
 	options   map[tiles.TileType]struct{} // remaining possible tiles
 	tile      tiles.TileType              // collapsed tile
@@ -28,37 +18,64 @@ type Cell struct {
 type Grid struct {
 	width, height int
 	cells         [][]*Cell
+	set           *tiles.Set
+	// adjacencyRules defines allowed neighbors for each tile.
+	adjacencyRules [][]tiles.TileType
+
+	// Progress, when set, is called every few collapses with the number of
+	// collapsed cells and the total number of cells.
+	Progress func(collapsed, total int)
+	// Watch, when set, receives the partial solution as often as Progress.
+	// Undecided cells are -1.
+	Watch func(grid [][]tiles.TileType)
 }
 
-// NewGrid initializes a grid with all tiles possible in each cell.
-func NewGrid(w, h int) *Grid {
-	g := &Grid{width: w, height: h}
+// checkEvery is the number of collapses between context checks and progress
+// reports.
+const checkEvery = 64
+
+// NewGrid initializes a grid with all tiles of set possible in each cell.
+// Tiles may only be placed next to their neighbors in the set; nil set is
+// tiles.Default.
+func NewGrid(w, h int, set *tiles.Set) *Grid {
+	if set == nil {
+		set = tiles.Default
+	}
+	g := &Grid{width: w, height: h, set: set}
+	for t := tiles.TileType(0); int(t) < set.Len(); t++ {
+		g.adjacencyRules = append(g.adjacencyRules, set.Neighbors(t))
+	}
 	g.cells = make([][]*Cell, h)
 	for y := 0; y < h; y++ {
 		g.cells[y] = make([]*Cell, w)
 		for x := 0; x < w; x++ {
 			// all tile types initially allowed
-			opts := make(map[tiles.TileType]struct{}, tiles.NumTileTypes)
-			for t := tiles.TileType(0); t < tiles.NumTileTypes; t++ {
-				opts[t] = struct{}{}
-			}
-			g.cells[y][x] = &Cell{options: opts}
+			g.cells[y][x] = &Cell{options: g.allOptions()}
 		}
 	}
 	return g
 }
 
+// allOptions returns a new option set holding every tile.
+func (g *Grid) allOptions() map[tiles.TileType]struct{} {
+	opts := make(map[tiles.TileType]struct{}, g.set.Len())
+	for t := tiles.TileType(0); int(t) < g.set.Len(); t++ {
+		opts[t] = struct{}{}
+	}
+	return opts
+}
+
 // Solve runs the WFC algorithm with a simple restart-on-conflict strategy.
-func (g *Grid) Solve(maxRetries int, seed int64) ([][]tiles.TileType, error) {
+// It stops with ctx.Err() when ctx is canceled.
+func (g *Grid) Solve(ctx context.Context, maxRetries int, seed int64) ([][]tiles.TileType, error) {
 
 	rng := rand.New(rand.NewSource(seed))
 
-	// Define water types
-	waterSet := map[tiles.TileType]struct{}{tiles.DeepWater: {}, tiles.Water: {}, tiles.CoastalWater: {}}
-
-	// Define land types
-	landSet := map[tiles.TileType]struct{}{
-		tiles.Sand: {}, tiles.Grass: {}, tiles.Bushes: {}, tiles.Forest: {},
+	// Water on the border and inland tiles in the center, when the set has both
+	waterSet := g.set.OfCategory(tiles.CategoryWater)
+	landSet := g.set.Inland()
+	if len(waterSet) == 0 || len(landSet) == 0 {
+		waterSet, landSet = nil, nil
 	}
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
@@ -66,18 +83,15 @@ func (g *Grid) Solve(maxRetries int, seed int64) ([][]tiles.TileType, error) {
 		for y := 0; y < g.height; y++ {
 			for x := 0; x < g.width; x++ {
 				g.cells[y][x].collapsed = false
-				g.cells[y][x].options = make(map[tiles.TileType]struct{}, tiles.NumTileTypes)
-				for t := tiles.TileType(0); t < tiles.NumTileTypes; t++ {
-					g.cells[y][x].options[t] = struct{}{}
-				}
+				g.cells[y][x].options = g.allOptions()
 			}
 		}
 		// Set water on the border
 		for y := 0; y < g.height; y++ {
 			for x := 0; x < g.width; x++ {
-				if x == 0 || y == 0 || x == g.width-1 || y == g.height-1 {
+				if waterSet != nil && (x == 0 || y == 0 || x == g.width-1 || y == g.height-1) {
 					opts := make(map[tiles.TileType]struct{}, len(waterSet))
-					for t := range waterSet {
+					for _, t := range waterSet {
 						opts[t] = struct{}{}
 					}
 					g.cells[y][x].options = opts
@@ -98,9 +112,9 @@ func (g *Grid) Solve(maxRetries int, seed int64) ([][]tiles.TileType, error) {
 					continue
 				}
 				dx := x - centerX
-				if dx*dx+dy*dy <= r2 {
+				if landSet != nil && dx*dx+dy*dy <= r2 {
 					opts := make(map[tiles.TileType]struct{}, len(landSet))
-					for t := range landSet {
+					for _, t := range landSet {
 						opts[t] = struct{}{}
 					}
 					g.cells[y][x].options = opts
@@ -113,36 +127,156 @@ func (g *Grid) Solve(maxRetries int, seed int64) ([][]tiles.TileType, error) {
 				}
 			}
 		}
-		// Collapse loop
-		ok := true
-		for {
-			x, y, found := g.findMinEntropy(rng)
-			if !found {
-				// Check for conflict
-				if g.anyCellHasNoOptions() {
-					ok = false
-				}
-				break
+		ok, err := g.run(ctx, rng)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return g.export(), nil
+		}
+	}
+	return nil, errors.New("WFC failed after retries")
+}
+
+// Repair keeps the legal parts of grid and re-solves every cell involved in an
+// illegal adjacency, together with the cells within radius of it. When the
+// solve fails the margin grows by one cell every ten retries. It returns the
+// repaired grid and the number of re-solved cells. It stops with ctx.Err()
+// when ctx is canceled.
+func (g *Grid) Repair(ctx context.Context, grid [][]tiles.TileType, radius, maxRetries int, seed int64) ([][]tiles.TileType, int, error) {
+	if len(grid) != g.height {
+		return nil, 0, errors.New("grid height does not match")
+	}
+	for _, row := range grid {
+		if len(row) != g.width {
+			return nil, 0, errors.New("grid width does not match")
+		}
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	// Cells that break a constraint, or hold an unknown tile type
+	broken := make([][2]int, 0)
+	for y := 0; y < g.height; y++ {
+		for x := 0; x < g.width; x++ {
+			if !g.legal(grid, x, y) {
+				broken = append(broken, [2]int{x, y})
 			}
-			if err := g.collapse(x, y, rng); err != nil {
-				ok = false
-				break
+		}
+	}
+	if len(broken) == 0 {
+		return grid, 0, nil
+	}
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		r := radius + attempt/10
+		free := make([][]bool, g.height)
+		for y := range free {
+			free[y] = make([]bool, g.width)
+		}
+		freed := 0
+		for _, b := range broken {
+			for y := b[1] - r; y <= b[1]+r; y++ {
+				for x := b[0] - r; x <= b[0]+r; x++ {
+					if x >= 0 && x < g.width && y >= 0 && y < g.height && !free[y][x] {
+						free[y][x] = true
+						freed++
+					}
+				}
 			}
-			if err := g.propagate(); err != nil {
-				ok = false
-				break
+		}
+		for y := 0; y < g.height; y++ {
+			for x := 0; x < g.width; x++ {
+				c := g.cells[y][x]
+				if free[y][x] {
+					c.collapsed = false
+					c.options = g.allOptions()
+				} else {
+					c.collapsed = true
+					c.tile = grid[y][x]
+					c.options = map[tiles.TileType]struct{}{c.tile: {}}
+				}
 			}
 		}
+		if g.propagate() != nil {
+			continue
+		}
+		ok, err := g.run(ctx, rng)
+		if err != nil {
+			return nil, 0, err
+		}
 		if ok {
-			return g.export(), nil
+			return g.export(), freed, nil
+		}
+	}
+	return nil, 0, errors.New("WFC repair failed after retries")
+}
+
+// legal reports whether the tile at (x, y) is known and allowed next to all
+// of its neighbors.
+func (g *Grid) legal(grid [][]tiles.TileType, x, y int) bool {
+	t := grid[y][x]
+	if !g.set.Valid(int(t)) {
+		return false
+	}
+	allowed := g.adjacencyRules[t]
+	for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+		nx, ny := x+d[0], y+d[1]
+		if ny < 0 || ny >= len(grid) || nx < 0 || nx >= len(grid[ny]) {
+			continue
+		}
+		if !slices.Contains(allowed, grid[ny][nx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// run collapses and propagates until every cell is decided. It reports false
+// on a conflict, and ctx.Err() once ctx is canceled.
+func (g *Grid) run(ctx context.Context, rng *rand.Rand) (bool, error) {
+	total := g.width * g.height
+	collapsed := 0
+	for y := 0; y < g.height; y++ {
+		for x := 0; x < g.width; x++ {
+			if g.cells[y][x].collapsed {
+				collapsed++
+			}
+		}
+	}
+	for step := 0; ; step++ {
+		if step%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			if g.Progress != nil {
+				g.Progress(collapsed, total)
+			}
+			if g.Watch != nil {
+				g.Watch(g.partial())
+			}
+		}
+		x, y, found := g.findMinEntropy(rng)
+		if !found {
+			// Check for conflict
+			ok := !g.anyCellHasNoOptions()
+			if ok && g.Progress != nil {
+				g.Progress(total, total)
+			}
+			return ok, nil
+		}
+		if err := g.collapse(x, y, rng); err != nil {
+			return false, nil
+		}
+		collapsed++
+		if err := g.propagate(); err != nil {
+			return false, nil
 		}
 	}
-	return nil, errors.New("WFC failed after retries")
 }
 
 // findMinEntropy picks a random cell with the fewest options (>1).
 func (g *Grid) findMinEntropy(rng *rand.Rand) (int, int, bool) {
-	minEntropy := tiles.NumTileTypes + 1
+	minEntropy := g.set.Len() + 1
 	var candidates [][2]int
 	for y := 0; y < g.height; y++ {
 		for x := 0; x < g.width; x++ {
@@ -154,10 +288,10 @@ func (g *Grid) findMinEntropy(rng *rand.Rand) (int, int, bool) {
 			if n == 0 {
 				return 0, 0, false // conflict
 			}
-			if n < int(minEntropy) {
-				minEntropy = tiles.TileType(n)
+			if n < minEntropy {
+				minEntropy = n
 				candidates = [][2]int{{x, y}}
-			} else if n == int(minEntropy) {
+			} else if n == minEntropy {
 				candidates = append(candidates, [2]int{x, y})
 			}
 		}
@@ -220,10 +354,7 @@ func (g *Grid) propagate() error {
 			}
 			ne := g.cells[ny][nx]
 			// collect allowed by all collapsed neighbors
-			allowed := make(map[tiles.TileType]struct{})
-			for t := tiles.TileType(0); t < tiles.NumTileTypes; t++ {
-				allowed[t] = struct{}{}
-			}
+			allowed := g.allOptions()
 			for _, d2 := range dirs {
 				x2, y2 := nx+d2[0], ny+d2[1]
 				if x2 < 0 || x2 >= g.width || y2 < 0 || y2 >= g.height {
@@ -234,7 +365,7 @@ func (g *Grid) propagate() error {
 					continue
 				}
 				tmp := make(map[tiles.TileType]struct{})
-				for _, t2 := range adjacencyRules[nbr.tile] {
+				for _, t2 := range g.adjacencyRules[nbr.tile] {
 					if _, ok := allowed[t2]; ok {
 						tmp[t2] = struct{}{}
 					}
@@ -266,6 +397,19 @@ func (g *Grid) export() [][]tiles.TileType {
 	return out
 }
 
+// partial returns the collapsed tiles, with -1 for undecided cells.
+func (g *Grid) partial() [][]tiles.TileType {
+	out := g.export()
+	for y := range out {
+		for x := range out[y] {
+			if !g.cells[y][x].collapsed {
+				out[y][x] = -1
+			}
+		}
+	}
+	return out
+}
+
 func (g *Grid) anyCellHasNoOptions() bool {
 	for y := 0; y < g.height; y++ {
 		for x := 0; x < g.width; x++ {
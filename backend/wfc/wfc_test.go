@@ -0,0 +1,115 @@
+package wfc
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"procedural-map-generation-toolkit/backend/tiles"
+)
+
+// violations counts the cells of grid that are unknown or break an adjacency.
+func violations(g *Grid, grid [][]tiles.TileType) int {
+	n := 0
+	for y := range grid {
+		for x := range grid[y] {
+			if !g.legal(grid, x, y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// legalGrid returns a grid of the size of g without violations.
+func legalGrid(t *testing.T, g *Grid) [][]tiles.TileType {
+	t.Helper()
+	// Solve seeds water on the border without checking it against the
+	// neighbors, so its result is repaired first
+	grid, err := g.Solve(context.Background(), 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid, _, err = g.Repair(context.Background(), grid, 1, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := violations(g, grid); n != 0 {
+		t.Fatalf("%d cells of the base grid break the adjacency rules", n)
+	}
+	return grid
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		// broken is the number of cells overwritten with a random tile
+		broken, radius int
+		// unknown writes tile types outside the set instead
+		unknown bool
+	}{
+		{0, 1, false},
+		{1, 1, false},
+		{10, 1, false},
+		{10, 0, false},
+		{30, 2, false},
+		{5, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("broken=%d/radius=%d/unknown=%t", tt.broken, tt.radius, tt.unknown), func(t *testing.T) {
+			const width, height = 20, 16
+			g := NewGrid(width, height, nil)
+			grid := legalGrid(t, g)
+			rng := rand.New(rand.NewSource(2))
+			input := make([][]tiles.TileType, height)
+			for y := range grid {
+				input[y] = append([]tiles.TileType(nil), grid[y]...)
+			}
+			for i := 0; i < tt.broken; i++ {
+				tile := tiles.TileType(rng.Intn(int(tiles.NumTileTypes)))
+				if tt.unknown {
+					tile = tiles.NumTileTypes + tile
+				}
+				input[rng.Intn(height)][rng.Intn(width)] = tile
+			}
+			before := violations(g, input)
+
+			repaired, resolved, err := g.Repair(context.Background(), input, tt.radius, 100, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := violations(g, repaired); n != 0 {
+				t.Errorf("%d cells still break the adjacency rules", n)
+			}
+			if before == 0 && resolved != 0 {
+				t.Errorf("re-solved %d cells of a legal grid", resolved)
+			}
+			if before > 0 && resolved == 0 {
+				t.Errorf("%d broken cells but none re-solved", before)
+			}
+			changed := 0
+			for y := range input {
+				for x := range input[y] {
+					if repaired[y][x] != input[y][x] {
+						changed++
+					}
+				}
+			}
+			if changed > resolved {
+				t.Errorf("%d cells changed, more than the %d re-solved", changed, resolved)
+			}
+		})
+	}
+}
+
+func TestRepairSize(t *testing.T) {
+	g := NewGrid(4, 3, nil)
+	for _, grid := range [][][]tiles.TileType{
+		make([][]tiles.TileType, 2),
+		{make([]tiles.TileType, 4), make([]tiles.TileType, 3), make([]tiles.TileType, 4)},
+	} {
+		if _, _, err := g.Repair(context.Background(), grid, 1, 10, 1); err == nil {
+			t.Errorf("Repair of a %d-row grid succeeded, want a size error", len(grid))
+		}
+	}
+}
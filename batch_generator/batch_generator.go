@@ -3,13 +3,20 @@ package main
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
+	"flag"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"procedural-map-generation-toolkit/backend/generator"
+	_ "procedural-map-generation-toolkit/backend/generator/methods"
+	"procedural-map-generation-toolkit/backend/render"
+	"procedural-map-generation-toolkit/backend/tiled"
+	"procedural-map-generation-toolkit/backend/tiles"
 )
 
 const (
@@ -20,21 +27,6 @@ const (
 	defaultHeight = 25
 )
 
-// GenerateRequest matches the structure expected by the backend
-type GenerateRequest struct {
-	GenerationMethod string  `json:"generationMethod"`
-	Width            int     `json:"width"`
-	Height           int     `json:"height"`
-	Iterations       int     `json:"iterations,omitempty"`
-	RandomnessFactor float64 `json:"randomnessFactor,omitempty"`
-	PaintedTiles     [][]int `json:"paintedTiles,omitempty"`
-	NoiseScale       float64 `json:"noiseScale,omitempty"`
-	NoiseOctaves     int     `json:"noiseOctaves,omitempty"`
-	NoisePersistence float64 `json:"noisePersistence,omitempty"`
-	NoiseLacunarity  float64 `json:"noiseLacunarity,omitempty"`
-	WFCSeed          *int64  `json:"wfcSeed,omitempty"` // Pointer to allow omission
-}
-
 // GenerateResponse matches the structure returned by the backend
 type GenerateResponse struct {
 	Grid        [][]int                   `json:"grid"`
@@ -49,106 +41,96 @@ type GenerateResponse struct {
 
 // ResultData is saved for each map
 type ResultData struct {
-	RequestParams    GenerateRequest  `json:"requestParams"`
-	ResponseMetrics  GenerateResponse `json:"responseMetrics"`
-	GenerationTimeMs int64            `json:"generationTimeMs"`
+	RequestParams    generator.Request `json:"requestParams"`
+	ResponseMetrics  GenerateResponse  `json:"responseMetrics"`
+	GenerationTimeMs int64             `json:"generationTimeMs"`
 }
 
-func main() {
-	methods := []string{"mlca", "noise", "wfc"}
+// imageFormat is the format of the image saved next to every result; empty
+// saves none
+var imageFormat = flag.String("image", render.FormatPNG, "render every map as png or svg; empty to skip")
+
+// tiledFormat is the Tiled format saved next to every result; empty saves
+// none. The maps of a method share one generated tileset.png.
+var tiledFormat = flag.String("tiled", "", "export every map as a Tiled tmx or tmj file")
+
+// tileSet is the tile set of every request; sets other than the built-in
+// ones are loaded from tileSetDir, which must match the server's
+var (
+	tileSet    = flag.String("tile-set", "", "tile set of the generated maps (default terrain)")
+	tileSetDir = flag.String("tilesets", "tilesets", "directory of tile set JSON files to load")
+)
 
+var (
+	tileset      tiled.Tileset
+	tilesetImage []byte
+)
+
+func main() {
+	flag.Parse()
+	if *imageFormat != "" && *imageFormat != render.FormatPNG && *imageFormat != render.FormatSVG {
+		log.Fatalf("Unknown image format %q", *imageFormat)
+	}
+	if *tiledFormat != "" && *tiledFormat != tiled.FormatTMX && *tiledFormat != tiled.FormatTMJ {
+		log.Fatalf("Unknown Tiled format %q", *tiledFormat)
+	}
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		log.Fatalf("Failed to create base output directory: %v", err)
 	}
+	if _, err := tiles.LoadDir(*tileSetDir); err != nil {
+		log.Fatalf("Failed to load tile sets: %v", err)
+	}
+	set, ok := tiles.Lookup(*tileSet)
+	if !ok {
+		log.Fatalf("Unknown tile set %q (available: %s)", *tileSet, strings.Join(tiles.Names(), ", "))
+	}
+	var err error
+	if tileset, tilesetImage, err = tiled.ColorTileset(set.Colors(), tiled.DefaultTileSize); err != nil {
+		log.Fatalf("Failed to build the tileset: %v", err)
+	}
 
-	for _, method := range methods {
+	for _, gen := range generator.All() {
+		method := gen.Name()
+		sampler, ok := gen.(generator.Sampler)
+		if !ok {
+			log.Printf("Skipping method %s: it defines no parameter sweep", method)
+			continue
+		}
 		log.Printf("Starting generation for method: %s", method)
 		methodDir := filepath.Join(outputDir, method)
 		if err := os.MkdirAll(methodDir, 0755); err != nil {
 			log.Fatalf("Failed to create output directory for %s: %v", method, err)
 		}
+		if *tiledFormat != "" {
+			if err := os.WriteFile(filepath.Join(methodDir, "tileset.png"), tilesetImage, 0644); err != nil {
+				log.Fatalf("Failed to write the tileset for %s: %v", method, err)
+			}
+		}
 
 		generatedCount := 0
-		switch method {
-		case "mlca":
-			// 10 randomnessFactor values * 10 iteration values = 100 maps
-			for i := 0; i < 10; i++ { // Iterations 1 to 10
-				iterations := i + 1
-				for j := 0; j < 10; j++ { // RandomnessFactor 0.0 to 0.9
-					if generatedCount >= mapsPerMethod {
-						break
-					}
-					randomness := float64(j) * 0.1
-					params := GenerateRequest{
-						GenerationMethod: method,
-						Width:            defaultWidth,
-						Height:           defaultHeight,
-						Iterations:       iterations,
-						RandomnessFactor: randomness,
-						PaintedTiles:     [][]int{},
-					}
-					filename := fmt.Sprintf("mlca_iter_%d_rand_%.2f.json", iterations, randomness)
-					generateAndSave(params, filepath.Join(methodDir, filename))
-					generatedCount++
-				}
-				if generatedCount >= mapsPerMethod {
-					break
-				}
+		for _, sample := range sampler.Samples(mapsPerMethod) {
+			params := sample.Request
+			params.GenerationMethod = method
+			params.TileSet = *tileSet
+			if params.Width == 0 {
+				params.Width = defaultWidth
 			}
-		case "noise":
-			// 10 noiseScale values * 10 noiseOctaves values = 100 maps
-			octaveSteps := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
-
-			// Default values
-			defaultPersistence := 0.9
-			defaultLacunarity := 1.8
-
-			for i := 0; i < 10; i++ { // noiseScale from 0.2 to 2.0
-				scale := 0.2 + (float64(i) * 0.2)
-				for j := 0; j < 10; j++ { // noiseOctaves
-					if generatedCount >= mapsPerMethod {
-						break
-					}
-					octaves := octaveSteps[j]
-					params := GenerateRequest{
-						GenerationMethod: method,
-						Width:            defaultWidth,
-						Height:           defaultHeight,
-						NoiseScale:       scale,
-						NoiseOctaves:     octaves,
-						NoisePersistence: defaultPersistence,
-						NoiseLacunarity:  defaultLacunarity,
-						PaintedTiles:     [][]int{},
-					}
-					filename := fmt.Sprintf("noise_scale_%.2f_oct_%d.json", scale, octaves)
-					generateAndSave(params, filepath.Join(methodDir, filename))
-					generatedCount++
-				}
-				if generatedCount >= mapsPerMethod {
-					break
-				}
+			if params.Height == 0 {
+				params.Height = defaultHeight
 			}
-		case "wfc":
-			for i := 0; i < mapsPerMethod; i++ {
-				seed := int64(i + 1) // WFCSeed 1 to 100
-				params := GenerateRequest{
-					GenerationMethod: method,
-					Width:            defaultWidth,
-					Height:           defaultHeight,
-					WFCSeed:          &seed, // Pass the pointer to the seed
-					PaintedTiles:     [][]int{},
-				}
-				filename := fmt.Sprintf("wfc_seed_%d.json", seed)
-				generateAndSave(params, filepath.Join(methodDir, filename))
-				generatedCount++
+			params.PaintedTiles = [][]int{}
+			if unknown := generator.Unsupported(gen, &params); len(unknown) > 0 {
+				log.Printf("Warning: sample %s sets parameters %s does not read: %v", sample.Name, method, unknown)
 			}
+			generateAndSave(params, filepath.Join(methodDir, sample.Name+".json"))
+			generatedCount++
 		}
 		log.Printf("Finished generation for method: %s, %d maps generated.", method, generatedCount)
 	}
 	log.Println("All generations complete.")
 }
 
-func generateAndSave(params GenerateRequest, outputPath string) {
+func generateAndSave(params generator.Request, outputPath string) {
 	log.Printf("Requesting: %s, Params: %+v", params.GenerationMethod, params)
 
 	startTime := time.Now()
@@ -209,6 +191,35 @@ func generateAndSave(params GenerateRequest, outputPath string) {
 		log.Printf("Error writing result file %s: %v", outputPath, err)
 		return
 	}
+	if *imageFormat != "" {
+		img, err := render.Render(*imageFormat, genResponse.Grid, genResponse.Colors, render.Options{})
+		if err != nil {
+			log.Printf("Error rendering %s: %v", outputPath, err)
+			return
+		}
+		imagePath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + *imageFormat
+		if err := os.WriteFile(imagePath, img, 0644); err != nil {
+			log.Printf("Error writing image file %s: %v", imagePath, err)
+			return
+		}
+	}
+	if *tiledFormat != "" {
+		tm, err := tiled.New([]tiled.Layer{{Name: "terrain", Grid: genResponse.Grid}}, tileset)
+		if err != nil {
+			log.Printf("Error exporting %s: %v", outputPath, err)
+			return
+		}
+		data, err := tm.Encode(*tiledFormat)
+		if err != nil {
+			log.Printf("Error exporting %s: %v", outputPath, err)
+			return
+		}
+		tiledPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + *tiledFormat
+		if err := os.WriteFile(tiledPath, data, 0644); err != nil {
+			log.Printf("Error writing Tiled file %s: %v", tiledPath, err)
+			return
+		}
+	}
 	log.Printf("Successfully generated and saved: %s (Time: %dms)", outputPath, generationTimeMs)
 	time.Sleep(100 * time.Millisecond) // Brief pause to avoid overwhelming the server
 }
@@ -1,13 +1,25 @@
-export async function getColors() {
-    const res = await fetch('/colors');
-    if (!res.ok) throw new Error('Failed to load colors from server');
+export async function getTileSets() {
+    const res = await fetch('/tilesets');
+    if (!res.ok) throw new Error('Failed to load tile sets from server');
     return res.json();
 }
 
-export async function saveCanvas(canvas) {
+export async function getMethods() {
+    const res = await fetch('/methods');
+    if (!res.ok) throw new Error('Failed to load generation methods from server');
+    return res.json();
+}
+
+/**
+ * Saves the canvas as a thumbnail together with the map grid and the request that generated it.
+ * @param {HTMLCanvasElement} canvas - The canvas to save.
+ * @param {object} map - {grid, request, tileSet, name, tags}; the grid makes the map reloadable.
+ */
+export async function saveCanvas(canvas, {grid, request, tileSet, name, tags} = {}) {
     const imageData = canvas.toDataURL('image/png');
-    const response = await fetch('/save', {
-        method: 'POST', headers: {'Content-Type': 'application/json'}, body: JSON.stringify({imageData}),
+    const response = await fetch('/maps', {
+        method: 'POST', headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify({imageData, grid, request, tileSet, name, tags, compress: true}),
     });
     if (!response.ok) {
         throw new Error('Failed to save the canvas image.');
@@ -15,22 +27,40 @@ export async function saveCanvas(canvas) {
     console.log('Canvas image saved successfully.');
 }
 
+/**
+ * Lets the user pick a saved map. Maps with a grid are returned for the editor, image-only saves are drawn as is.
+ * @param {HTMLCanvasElement} canvas - The canvas image-only saves are drawn to.
+ * @returns {Promise<object|null>} The saved map from /maps/{id}, or null.
+ */
 export async function loadCanvasTo(canvas) {
-    const response = await fetch('/load');
+    const response = await fetch('/maps');
     if (!response.ok) {
         throw new Error('Failed to load map images.');
     }
-    const imageFiles = await response.json();
-    const selectedFile = prompt('Choose a map file to load:', imageFiles.join(', '));
-    if (selectedFile) {
-        const img = new Image();
-        img.onload = () => {
-            const ctx = canvas.getContext('2d');
-            ctx.clearRect(0, 0, canvas.width, canvas.height);
-            ctx.drawImage(img, 0, 0);
-        };
-        img.src = `/maps/${selectedFile}`;
+    const maps = await response.json();
+    const describe = m => {
+        const tags = m.tags.length ? ` [${m.tags.join(', ')}]` : '';
+        const info = m.file ? ` (${m.generationMethod || 'painted'} ${m.width}x${m.height})` : '';
+        return `${m.id}: ${m.name}${info}${tags}`;
+    };
+    const selected = prompt('Choose a map id to load:\n' + maps.map(describe).join('\n'));
+    const map = maps.find(m => m.id === selected?.trim());
+    if (!map) return null;
+
+    if (map.file) {
+        const res = await fetch(`/maps/${encodeURIComponent(map.id)}`);
+        if (!res.ok) throw new Error(`Failed to load ${map.name}.`);
+        return (await res.json()).map;
     }
+    const img = new Image();
+    img.onload = () => {
+        const ctx = canvas.getContext('2d');
+        ctx.clearRect(0, 0, canvas.width, canvas.height);
+        ctx.drawImage(img, 0, 0);
+        window.clearLockedCells();
+    };
+    img.src = `/maps/files/${map.thumbnail}`;
+    return null;
 }
 
 export async function generate(params) {
@@ -39,8 +69,58 @@ export async function generate(params) {
     });
 
     if (!response.ok) {
-        const errorText = await response.text();
-        throw new Error(`Failed to generate the map: ${response.status} ${errorText}`);
+        throw new Error(`Failed to generate the map: ${response.status}\n${errorDetails(await response.text())}`);
     }
     return response.json();
+}
+
+/**
+ * Generates a map via /generate/stream, reporting intermediate grids while it runs.
+ * @param {object} params - The /generate request.
+ * @param {object} handlers - onFrame(frame) and onProgress({done, total}), both optional.
+ * @param {number} fps - Maximum frame rate sent by the server.
+ * @returns {Promise<object>} The final /generate response.
+ */
+export async function generateStream(params, {onFrame, onProgress} = {}, fps = 10) {
+    const response = await fetch(`/generate/stream?fps=${fps}`, {
+        method: 'POST', headers: {'Content-Type': 'application/json'}, body: JSON.stringify(params),
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to generate the map: ${response.status}\n${errorDetails(await response.text())}`);
+    }
+
+    const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffer = '';
+    for (;;) {
+        const {value, done} = await reader.read();
+        if (done) break;
+        buffer += value;
+        // Events are separated by a blank line
+        let end;
+        while ((end = buffer.indexOf('\n\n')) >= 0) {
+            const lines = buffer.slice(0, end).split('\n');
+            buffer = buffer.slice(end + 2);
+            const event = lines.find(l => l.startsWith('event: '))?.slice(7);
+            const data = JSON.parse(lines.filter(l => l.startsWith('data: ')).map(l => l.slice(6)).join('\n'));
+            if (event === 'frame' && onFrame) onFrame(data);
+            if (event === 'progress' && onProgress) onProgress(data);
+            if (event === 'result') return data;
+            if (event === 'error') {
+                throw new Error(`Failed to generate the map\n${errorDetails(JSON.stringify(data))}`);
+            }
+        }
+    }
+    throw new Error('Generation stream ended without a result');
+}
+
+function errorDetails(errorText) {
+    try {
+        // Invalid requests list the offending fields
+        const {errors, error} = JSON.parse(errorText);
+        if (errors) return errors.map(e => `${e.field}: ${e.message}`).join('\n');
+        if (error) return error;
+    } catch (e) {
+        // Not JSON, show as is
+    }
+    return errorText;
 }
\ No newline at end of file
@@ -16,6 +16,60 @@ export function renderGrid(canvas, grid, tileColors) {
     });
 }
 
+/**
+ * Draws a frame streamed by /generate/stream: a whole grid or the changed cells.
+ * Undecided cells (-1) are left as they are.
+ * @param {HTMLCanvasElement} canvas - The canvas element to draw on.
+ * @param {object} frame - The frame event data.
+ * @param {string[]} tileColors - The array of color strings.
+ */
+export function renderFrame(canvas, frame, tileColors) {
+    const ctx = canvas.getContext('2d');
+    const draw = (x, y, tile) => {
+        if (tile < 0) return;
+        ctx.fillStyle = tileColors[tile] || '#000000';
+        ctx.fillRect(x * TileSize, y * TileSize, TileSize, TileSize);
+    };
+    if (frame.grid) {
+        frame.grid.forEach((row, y) => row.forEach((tile, x) => draw(x, y, tile)));
+    }
+    (frame.deltas || []).forEach(([x, y, tile]) => draw(x, y, tile));
+}
+
+/**
+ * Plays back a time-lapse returned by the /generate endpoint.
+ * Full and delta encoded frames are supported.
+ * @param {HTMLCanvasElement} canvas - The canvas element to draw on.
+ * @param {object} timelapse - The timelapse object from the server response.
+ * @param {string[]} tileColors - The array of color strings.
+ * @param {number} frameDelay - Milliseconds between frames.
+ * @returns {Promise<void>} Resolves after the last frame has been drawn.
+ */
+export async function playTimelapse(canvas, timelapse, tileColors, frameDelay = 200) {
+    if (!timelapse || !timelapse.frames || timelapse.frames.length === 0) return;
+    const sleep = ms => new Promise(resolve => setTimeout(resolve, ms));
+
+    if (timelapse.encoding !== 'delta') {
+        for (const frame of timelapse.frames) {
+            renderGrid(canvas, frame, tileColors);
+            await sleep(frameDelay);
+        }
+        return;
+    }
+
+    const grid = timelapse.frames[0].map(row => row.slice());
+    renderGrid(canvas, grid, tileColors);
+    const ctx = canvas.getContext('2d');
+    for (const changes of timelapse.deltas || []) {
+        await sleep(frameDelay);
+        changes.forEach(([x, y, tile]) => {
+            grid[y][x] = tile;
+            ctx.fillStyle = tileColors[tile] || '#000000';
+            ctx.fillRect(x * TileSize, y * TileSize, TileSize, TileSize);
+        });
+    }
+}
+
 /**
  * Reads the painted tiles from the canvas and converts them to a grid of tile indices.
  * @param {HTMLCanvasElement} canvas - The canvas element to read from.
@@ -36,6 +90,24 @@ export function getPaintedTiles(canvas) {
     return tiles;
 }
 
+/**
+ * Builds the lock mask for cells painted as hard constraints.
+ * @param {HTMLCanvasElement} canvas - The paint canvas.
+ * @returns {boolean[][]} A 2D array, true where a cell is locked.
+ */
+export function getLockedTiles(canvas) {
+    const rows = Math.ceil(canvas.height / TileSize);
+    const cols = Math.ceil(canvas.width / TileSize);
+    const locked = Array.from({length: rows}, () => Array(cols).fill(false));
+    window.lockedCells.forEach(key => {
+        const [x, y] = key.split(',').map(Number);
+        if (y >= 0 && y < rows && x >= 0 && x < cols) {
+            locked[y][x] = true;
+        }
+    });
+    return locked;
+}
+
 /**
  * Determines the tile index for a given pixel's color data.
  * @param {ImageData} imageData - The ImageData from a canvas pixel.
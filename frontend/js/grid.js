@@ -10,6 +10,7 @@ export function initGrid() {
     const paintCanvas = document.getElementById('paint-canvas');
     paintCanvas.width = GridSize;
     paintCanvas.height = GridSize;
+    window.clearLockedCells();
 
     drawGrid();
 }
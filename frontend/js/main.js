@@ -1,13 +1,18 @@
 import * as api from './api.js';
 import * as ui from './ui.js';
 import {updateMetricsPanel} from './ui.js';
-import {getPaintedTiles, renderGrid} from './canvas.js';
+import {getLockedTiles, getPaintedTiles, playTimelapse, renderFrame, renderGrid} from './canvas.js';
 import {initGrid, TileSize} from './grid.js';
 import {initExportButtons} from './export.js';
+import {getMethodParams, initMethodControls, setMethodParams} from './methods.js';
 
 // --- Main Application State ---
 const state = {
     paintCanvas: null,
+    // Grid and request of the last generation; the request is saved with the map
+    lastMap: null,
+    // Tile sets from GET /tilesets by name
+    tileSets: {},
 };
 
 // --- Event Handlers ---
@@ -20,22 +25,39 @@ async function handleGenerate() {
             width: Math.ceil(paintCanvas.width / TileSize),
             height: Math.ceil(paintCanvas.height / TileSize),
             paintedTiles: getPaintedTiles(paintCanvas),
+            lockedTiles: getLockedTiles(paintCanvas),
             generationMethod: document.getElementById('generation-method').value,
+            tileSet: document.getElementById('tile-set').value,
 
             // Read slider values
             iterations: Number(document.getElementById('iteration-slider').value),
             randomnessFactor: parseFloat(document.getElementById('randomness-slider').value),
 
-            // Example noise params
-            noiseScale: 0.5,
-            noiseOctaves: 4,
-            noisePersistence: 0.9,
-            noiseLacunarity: 1.8,
+            // Method-specific params from the controls built by GET /methods
+            ...getMethodParams(),
         };
 
+        if (document.getElementById('live-toggle').checked) {
+            // Draw intermediate grids while the server generates
+            const data = await api.generateStream(params, {
+                onFrame: frame => renderFrame(paintCanvas, frame, window.tileColors),
+            });
+            renderGrid(paintCanvas, data.grid, data.colors);
+            updateMetricsPanel(data);
+            state.lastMap = {grid: data.grid, request: params};
+            return;
+        }
+
+        if (document.getElementById('timelapse-toggle').checked) {
+            params.snapshotEvery = 1;
+            params.snapshotEncoding = 'delta';
+        }
+
         const data = await api.generate(params);
         console.log('Server response: ', data);
+        state.lastMap = {grid: data.grid, request: params};
 
+        await playTimelapse(paintCanvas, data.timelapse, data.colors);
         renderGrid(paintCanvas, data.grid, data.colors);
         updateMetricsPanel(data);
 
@@ -47,7 +69,16 @@ async function handleGenerate() {
 
 async function handleSave() {
     try {
-        await api.saveCanvas(state.paintCanvas);
+        // The grid is read from the canvas, so paint edits since the last generation are kept
+        const map = {
+            grid: getPaintedTiles(state.paintCanvas),
+            request: state.lastMap?.request,
+            tileSet: document.getElementById('tile-set').value,
+        };
+        const name = prompt('Map name:', map.request?.generationMethod || 'painted');
+        if (name === null) return;
+        const tags = (prompt('Tags, separated by commas:', '') || '').split(',');
+        await api.saveCanvas(state.paintCanvas, {...map, name, tags});
     } catch (error) {
         console.error('Error in handleSave: ', error);
         alert(error.message);
@@ -56,19 +87,81 @@ async function handleSave() {
 
 async function handleLoad() {
     try {
-        await api.loadCanvasTo(state.paintCanvas);
+        const map = await api.loadCanvasTo(state.paintCanvas);
+        if (map) {
+            selectTileSet(map.tileSet || map.request?.tileSet);
+            renderGrid(state.paintCanvas, map.grid, map.palette);
+            window.clearLockedCells();
+            state.lastMap = {grid: map.grid, request: map.request};
+            if (map.request) restoreControls(map.request);
+        }
     } catch (error) {
         console.error('Error in handleLoad: ', error);
         alert(error.message);
     }
 }
 
+/**
+ * Sets the method, slider and parameter controls from a saved request.
+ * @param {object} request - The /generate request of a saved map.
+ */
+function restoreControls(request) {
+    selectTileSet(request.tileSet);
+    const method = document.getElementById('generation-method');
+    method.value = request.generationMethod;
+    method.dispatchEvent(new Event('change'));
+    for (const [id, value] of [['iteration-slider', request.iterations], ['randomness-slider', request.randomnessFactor]]) {
+        // Maps saved without iterations keep the current slider value
+        if (value === undefined) continue;
+        const slider = document.getElementById(id);
+        slider.value = value;
+        slider.dispatchEvent(new Event('input'));
+    }
+    setMethodParams(request);
+}
+
+/**
+ * Selects a tile set for painting and generation.
+ * @param {string} [name] - The tile set name; empty selects the default set.
+ */
+function selectTileSet(name) {
+    const select = document.getElementById('tile-set');
+    const set = state.tileSets[name || 'terrain'];
+    if (!set) return;
+    select.value = set.name;
+    window.tileColors = set.tiles.map(t => t.color);
+    ui.updatePaintButtons(set);
+}
+
+/**
+ * Fills the tile set select; changing the set rebuilds the parameter controls
+ * for its tile range.
+ * @param {Array<object>} tileSets - The response of GET /tilesets.
+ */
+function initTileSets(tileSets) {
+    state.tileSets = Object.fromEntries(tileSets.map(s => [s.name, s]));
+    const select = document.getElementById('tile-set');
+    tileSets.forEach(({name, description}) => {
+        const option = document.createElement('option');
+        option.value = name;
+        option.textContent = name;
+        option.title = description || '';
+        select.appendChild(option);
+    });
+    select.addEventListener('change', () => {
+        selectTileSet(select.value);
+        document.getElementById('generation-method').dispatchEvent(new Event('change'));
+    });
+    selectTileSet();
+}
+
 // --- Initialization ---
 
 document.addEventListener('DOMContentLoaded', async () => {
     try {
         // Load critical data
-        window.tileColors = await api.getColors();
+        initTileSets(await api.getTileSets());
+        initMethodControls(await api.getMethods());
 
         // Initialize UI components
         initGrid();
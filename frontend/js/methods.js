@@ -0,0 +1,128 @@
+// Parameters that already have their own controls or are filled from the canvas
+const FIXED_PARAMS = new Set(['width', 'height', 'iterations', 'randomnessFactor', 'tileSet']);
+const CONTROL_TYPES = new Set(['int', 'float', 'bool', 'string', 'tile']);
+
+let methodsByName = {};
+
+/**
+ * Fills the generation method select from the server's method list and
+ * builds the parameter controls of the selected method.
+ * @param {Array<object>} methods - The response of GET /methods.
+ */
+export function initMethodControls(methods) {
+    methodsByName = Object.fromEntries(methods.map(m => [m.name, m]));
+
+    const select = document.getElementById('generation-method');
+    const labels = Object.fromEntries([...select.options].map(o => [o.value, o.textContent]));
+    const selected = select.value;
+    select.innerHTML = '';
+    methods.forEach(({name, description}) => {
+        const option = document.createElement('option');
+        option.value = name;
+        option.textContent = labels[name] || name;
+        option.title = description;
+        select.appendChild(option);
+    });
+    if (methodsByName[selected]) {
+        select.value = selected;
+    }
+
+    select.addEventListener('change', () => renderParams(select.value));
+    renderParams(select.value);
+}
+
+function renderParams(methodName) {
+    const container = document.getElementById('method-params');
+    container.innerHTML = '';
+    const method = methodsByName[methodName];
+    if (!method) return;
+
+    method.params
+        .filter(p => CONTROL_TYPES.has(p.type) && !FIXED_PARAMS.has(p.name))
+        .forEach(param => {
+            const row = document.createElement('div');
+            const label = document.createElement('label');
+            label.htmlFor = `param-${param.name}`;
+            label.textContent = `${param.name}:`;
+            label.title = param.description;
+            row.appendChild(label);
+            row.appendChild(createInput(param));
+            container.appendChild(row);
+        });
+}
+
+function createInput(param) {
+    let input;
+    if (param.enum) {
+        input = document.createElement('select');
+        param.enum.forEach(value => {
+            const option = document.createElement('option');
+            option.value = value;
+            option.textContent = value;
+            input.appendChild(option);
+        });
+        if (param.default !== undefined) input.value = param.default;
+    } else if (param.type === 'bool') {
+        input = document.createElement('input');
+        input.type = 'checkbox';
+        input.checked = Boolean(param.default);
+    } else if (param.type === 'string') {
+        input = document.createElement('input');
+        input.type = 'text';
+        input.value = param.default ?? '';
+    } else {
+        input = document.createElement('input');
+        input.type = 'number';
+        input.step = param.type === 'float' ? 'any' : '1';
+        if (param.min !== undefined) input.min = param.min;
+        if (param.max !== undefined) input.max = param.max;
+        if (param.type === 'tile') {
+            // Left empty so the server applies the default of the selected tile set
+            input.min = 0;
+            input.max = (window.tileColors || []).length - 1;
+            input.placeholder = 'default';
+        } else if (param.default !== undefined) {
+            input.value = param.default;
+        }
+    }
+    input.id = `param-${param.name}`;
+    input.dataset.param = param.name;
+    input.dataset.type = param.type;
+    input.title = param.description;
+    return input;
+}
+
+/**
+ * Returns the values of the parameter controls, leaving out empty fields so
+ * the server applies its defaults.
+ * @returns {object} Request fields keyed by parameter name.
+ */
+export function getMethodParams() {
+    const params = {};
+    document.querySelectorAll('#method-params [data-param]').forEach(input => {
+        const {param, type} = input.dataset;
+        if (type === 'bool') {
+            params[param] = input.checked;
+        } else if (input.value !== '') {
+            params[param] = type === 'int' || type === 'tile' ? parseInt(input.value, 10)
+                : type === 'float' ? parseFloat(input.value) : input.value;
+        }
+    });
+    return params;
+}
+
+/**
+ * Fills the parameter controls from a request, e.g. of a saved map.
+ * @param {object} request - Request fields keyed by parameter name.
+ */
+export function setMethodParams(request) {
+    document.querySelectorAll('#method-params [data-param]').forEach(input => {
+        const value = request[input.dataset.param];
+        if (value === undefined || value === null) return;
+        if (input.dataset.type === 'bool') {
+            input.checked = Boolean(value);
+        } else {
+            input.value = value;
+        }
+    });
+}
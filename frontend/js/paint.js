@@ -1,11 +1,24 @@
 import {TileSize} from "./grid.js";
 
 let paintColor = null;
+let paintHard = false;
+
+// Cells painted as hard constraints, stored as "x,y" keys
+window.lockedCells = new Set();
+
+// Drops all locks, when the user clears them or loads a map; generating keeps them
+window.clearLockedCells = function () {
+    window.lockedCells.clear();
+}
 
 window.setPaintColor = function (color) {
     paintColor = color;
 }
 
+window.setPaintHard = function (hard) {
+    paintHard = hard;
+}
+
 function initPainting() {
     const canvas = document.getElementById('paint-canvas');
     const ctx = canvas.getContext('2d');
@@ -43,7 +56,14 @@ function initPainting() {
         ctx.fillStyle = paintColor;
         for (let y = 0; y < brushSize; y++) {
             for (let x = 0; x < brushSize; x++) {
-                ctx.fillRect((centerCellX + x - halfBrush) * TileSize, (centerCellY + y - halfBrush) * TileSize, TileSize, TileSize);
+                const cellX = centerCellX + x - halfBrush;
+                const cellY = centerCellY + y - halfBrush;
+                ctx.fillRect(cellX * TileSize, cellY * TileSize, TileSize, TileSize);
+                if (paintHard) {
+                    window.lockedCells.add(`${cellX},${cellY}`);
+                } else {
+                    window.lockedCells.delete(`${cellX},${cellY}`);
+                }
             }
         }
     }
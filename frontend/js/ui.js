@@ -348,6 +348,26 @@ export function updateMetricsPanel(data) {
     visualizeSpectrum(data);
 }
 
+// Paint buttons for the first, middle and last tile of the tile set
+const PAINT_BUTTONS = ['paint-water', 'paint-sand', 'paint-forest'];
+
+/**
+ * Points the paint buttons at the first, middle and last tile of a tile set,
+ * e.g. deep water, sand and forest.
+ * @param {object} set - A tile set from GET /tilesets.
+ */
+export function updatePaintButtons(set) {
+    const n = set.tiles.length;
+    [0, Math.floor(n / 2), n - 1].forEach((index, i) => {
+        const tile = set.tiles[index];
+        const button = document.getElementById(PAINT_BUTTONS[i]);
+        button.textContent = tile.name;
+        button.title = tile.category;
+        button.style.backgroundColor = tile.color;
+        button.dataset.tile = tile.id;
+    });
+}
+
 /**
  * Initializes all buttons and sliders in the UI.
  * @param {object} callbacks - An object with callback functions for button clicks.
@@ -362,16 +382,19 @@ export function initControls(callbacks) {
     document.getElementById('load-btn').addEventListener('click', callbacks.onLoad);
 
     // --- Paint Color Buttons ---
-    const paintConfig = [{id: 'paint-water', tileIndex: 0}, {id: 'paint-sand', tileIndex: 4}, {
-        id: 'paint-forest', tileIndex: 7
-    },];
-    paintConfig.forEach(({id, tileIndex}) => {
-        document.getElementById(id).addEventListener('click', () => {
-            const color = window.tileColors[tileIndex];
+    PAINT_BUTTONS.forEach(id => {
+        const button = document.getElementById(id);
+        button.addEventListener('click', () => {
+            const color = window.tileColors[button.dataset.tile];
             window.setPaintColor(color);
         });
     });
 
+    document.getElementById('paint-hard-toggle').addEventListener('change', (event) => {
+        window.setPaintHard(event.target.checked);
+    });
+    document.getElementById('clear-locks-btn').addEventListener('click', () => window.clearLockedCells());
+
     // --- Sliders ---
     const brushSizeSlider = document.getElementById('brushSize-slider');
     const brushSizeValue = document.getElementById('brushSize-value');